@@ -0,0 +1,197 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// bitField is a field tagged with `binary:"bits=N"`.
+type bitField struct {
+	Index int  // The index of the field, -1 to skip it when resolving
+	Bits  uint // The width of the field in bits
+	Kind  Kind // The kind of the writer field
+}
+
+// bitGroupCodec packs consecutive bitfields of a struct into shared bytes, most
+// significant bit first, padding the last byte with zeros. It reads and writes
+// the whole struct, so its fieldCodec has an Index of -1.
+type bitGroupCodec []bitField
+
+// newBitField parses the width of a bitfield and checks it suits the field.
+func newBitField(field reflect.StructField, index int, width string) (bitField, error) {
+	f := bitField{Index: index, Kind: Kind(field.Type.Kind())}
+	for _, c := range width {
+		if c < '0' || c > '9' || f.Bits > 64 {
+			return f, Err("bits", field.Name, width, D.Invalid)
+		}
+		f.Bits = f.Bits*10 + uint(c-'0')
+	}
+
+	switch {
+	case f.Bits == 0 || f.Bits > 64:
+		return f, Err("bits", field.Name, width, D.Invalid)
+	case f.signed(), f.unsigned(), f.Kind == K.Bool:
+		return f, nil
+	}
+	return f, Err("bits", D.Type, field.Type.String(), D.Not, D.Supported)
+}
+
+func (f bitField) signed() bool {
+	return signedKind(f.Kind)
+}
+
+func (f bitField) unsigned() bool {
+	return unsignedKind(f.Kind)
+}
+
+// size returns the number of bytes taken by the group.
+func (c bitGroupCodec) size() int {
+	var total uint
+	for _, f := range c {
+		total += f.Bits
+	}
+	return int((total + 7) / 8)
+}
+
+// Encode encodes a value into the encoder.
+func (c bitGroupCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	w := bitWriter{buf: make([]byte, 0, c.size())}
+	for _, f := range c {
+		field := rv.Field(f.Index)
+		var bits uint64
+		switch {
+		case f.Kind == K.Bool:
+			if field.Bool() {
+				bits = 1
+			}
+		case f.signed():
+			v := field.Int()
+			if f.Bits < 64 && (v < -1<<(f.Bits-1) || v >= 1<<(f.Bits-1)) {
+				return Errf("value %d of field %s overflows %d bits", v, rv.Type().Field(f.Index).Name, f.Bits)
+			}
+			bits = uint64(v) & (1<<f.Bits - 1)
+		default:
+			v := field.Uint()
+			if f.Bits < 64 && v >= 1<<f.Bits {
+				return Errf("value %d of field %s overflows %d bits", v, rv.Type().Field(f.Index).Name, f.Bits)
+			}
+			bits = v
+		}
+		w.writeBits(bits, f.Bits)
+	}
+
+	e.Write(w.buf)
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c bitGroupCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	return c.read(d, func(f bitField, v any) error {
+		if f.Index < 0 {
+			return nil
+		}
+
+		field := rv.Field(f.Index)
+		switch x := v.(type) {
+		case bool:
+			field.SetBool(x)
+			return nil
+		case int64:
+			if field.OverflowInt(x) {
+				return Errf("value %d overflows %s", x, field.Type().String())
+			}
+			field.SetInt(x)
+		case uint64:
+			if field.OverflowUint(x) {
+				return Errf("value %d overflows %s", x, field.Type().String())
+			}
+			field.SetUint(x)
+		}
+		return nil
+	})
+}
+
+// read reads the group and passes each field with its value, a bool, an int64
+// sign extended from the width of the field or an uint64.
+func (c bitGroupCodec) read(d *decoder, set func(f bitField, v any) error) error {
+	packed, err := d.Slice(c.size())
+	if err != nil {
+		return err
+	}
+
+	r := bitReader{buf: packed}
+	for _, f := range c {
+		bits, err := r.readBits(f.Bits)
+		if err != nil {
+			return err
+		}
+
+		var v any = bits
+		switch {
+		case f.Kind == K.Bool:
+			v = bits != 0
+		case f.signed():
+			v = int64(bits<<(64-f.Bits)) >> (64 - f.Bits)
+		}
+		if err = set(f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// bitWidth returns the width of a bitfield described by the schema, or 0 for
+// other fields.
+func (s *Schema) bitWidth() uint {
+	width, ok := tagOptions(s.Tag).Get("bits")
+	if !ok {
+		return 0
+	}
+	var n uint
+	for _, c := range width {
+		if c < '0' || c > '9' || n > 64 {
+			return 0
+		}
+		n = n*10 + uint(c-'0')
+	}
+	if n > 64 {
+		return 0
+	}
+	return n
+}
+
+// bitRun returns the end of the run of bitfields starting at field i, or i+1
+// when field i is not a bitfield.
+func bitRun(fields []Schema, i int) int {
+	j := i + 1
+	if fields[i].bitWidth() > 0 {
+		for j < len(fields) && fields[j].bitWidth() > 0 {
+			j++
+		}
+	}
+	return j
+}
+
+// schemaBitGroup returns the group codec of a run of bitfields in the writer
+// schema, without any target field.
+func schemaBitGroup(fields []Schema) bitGroupCodec {
+	group := make(bitGroupCodec, len(fields))
+	for i := range fields {
+		group[i] = bitField{Index: -1, Bits: fields[i].bitWidth(), Kind: fields[i].Kind}
+	}
+	return group
+}
+
+// decodeBitValues decodes a run of bitfields without a Go type.
+func decodeBitValues(d *decoder, fields []Schema) ([]Value, error) {
+	values := make([]Value, 0, len(fields))
+	err := schemaBitGroup(fields).read(d, func(f bitField, v any) error {
+		s := &fields[len(values)]
+		values = append(values, Value{Name: s.Name, Kind: s.Kind, Scalar: v})
+		return nil
+	})
+	return values, err
+}
@@ -0,0 +1,197 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+type loraPacket struct {
+	Version  uint8 `binary:"bits=3"`
+	Alarm    bool  `binary:"bits=1"`
+	Channel  uint8 `binary:"bits=4"`
+	Temp     int16 `binary:"bits=12"`
+	Battery  uint8 `binary:"bits=5"`
+	Reserved uint8 `binary:"bits=3"`
+	Counter  uint32
+	Delta    int8 `binary:"bits=5"`
+}
+
+func TestBitfieldLayout(t *testing.T) {
+	tb := New()
+	in := loraPacket{Version: 5, Alarm: true, Channel: 9, Temp: -300, Battery: 31, Reserved: 0, Counter: 300, Delta: -16}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		0b101_1_1001,                          // Version, Alarm, Channel
+		0b11101101, 0b0100_1111, 0b1_000_0000, // Temp (12), Battery (5), Reserved (3), padding
+		0xAC, 0x02, // Counter as a varint
+		0b10000_000, // Delta (5), padding
+	}, data)
+
+	var out loraPacket
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestBitfieldRange(t *testing.T) {
+	tb := New()
+	for _, in := range []loraPacket{
+		{Version: 8},
+		{Temp: 2048},
+		{Temp: -2049},
+		{Delta: 16},
+	} {
+		if _, err := tb.Encode(&in); err == nil {
+			t.Errorf("Expected range error encoding %+v", in)
+		}
+	}
+
+	// Bounds are accepted
+	in := loraPacket{Version: 7, Temp: -2048, Delta: 15, Battery: 31}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out loraPacket
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestBitfieldFullWidth(t *testing.T) {
+	type wide struct {
+		Low  uint64 `binary:"bits=64"`
+		High int64  `binary:"bits=64"`
+		Flag bool   `binary:"bits=1"`
+	}
+
+	tb := New()
+	in := wide{Low: math.MaxUint64, High: math.MinInt64, Flag: true}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualInt(t, 17, len(data))
+
+	var out wide
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestBitfieldInvalidTags(t *testing.T) {
+	tb := New()
+	if _, err := tb.Encode(&struct {
+		Name string `binary:"bits=3"`
+	}{}); err == nil {
+		t.Error("Expected error for a string bitfield")
+	}
+	for _, in := range []any{
+		&struct {
+			V uint8 `binary:"bits=0"`
+		}{},
+		&struct {
+			V uint8 `binary:"bits=65"`
+		}{},
+		&struct {
+			V uint8 `binary:"bits=x"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for invalid width in %T", in)
+		}
+	}
+
+	// Bitfields take no other option
+	for _, in := range []any{
+		&struct {
+			V uint16 `binary:"bits=12,fixed"`
+		}{},
+		&struct {
+			V uint16 `binary:"bits=12,be"`
+		}{},
+		&struct {
+			V uint8 `binary:"bits=4,encrypt"`
+		}{},
+		&struct {
+			V uint8 `binary:"bits=4,size=2"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for bitfield options in %T", in)
+		}
+	}
+}
+
+func TestBitfieldSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(loraPacket{})
+	assertNoError(t, err)
+	assertEqual(t, wireBits, schema.Fields[0].wire())
+
+	in := loraPacket{Version: 2, Channel: 7, Temp: -5, Battery: 20, Counter: 99, Delta: -3}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	// Readers may pick a subset of the bitfields, into plain fields
+	var subset struct {
+		Temp    int32
+		Counter uint32
+		Delta   int8 `binary:"bits=5"`
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, int32(-5), subset.Temp)
+	assertEqual(t, uint32(99), subset.Counter)
+	assertEqual(t, int8(-3), subset.Delta)
+
+	var mismatch struct{ Temp uint16 }
+	if err := tb.DecodeWithWriterSchema(data, schema, &mismatch); err == nil {
+		t.Error("Expected error resolving a signed bitfield into an unsigned field")
+	}
+
+	// Dynamic values
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, loraPacket{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	temp, _ := v.Field("Temp")
+	assertEqual(t, int64(-5), temp.Scalar)
+	battery, _ := v.Field("Battery")
+	assertEqual(t, uint64(20), battery.Scalar)
+	counter, _ := v.Field("Counter")
+	assertEqual(t, uint64(99), counter.Scalar)
+
+	// Width changes are reported
+	type narrower struct {
+		Version uint8 `binary:"bits=2"`
+	}
+	cur, err := tb.Schema(narrower{})
+	assertNoError(t, err)
+	old, err := tb.Schema(struct {
+		Version uint8 `binary:"bits=3"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "bit width changed", issues[0].Reason)
+}
+
+func TestBitfieldColumnar(t *testing.T) {
+	type batch struct {
+		Packets []loraPacket `binary:"columnar"`
+	}
+
+	tb := New()
+	in := batch{Packets: []loraPacket{{Version: 1, Temp: -1, Counter: 5}, {Version: 2, Alarm: true, Delta: 4}}}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out batch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	schema, err := tb.Schema(batch{})
+	assertNoError(t, err)
+	var none struct{}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &none))
+}
@@ -0,0 +1,63 @@
+package tinybin
+
+import "io"
+
+// bitWriter appends values of any bit width to a byte slice, most significant
+// bit first.
+type bitWriter struct {
+	buf  []byte
+	free uint // unused low bits of the last byte
+}
+
+// writeBits writes the n low bits of v.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := min(n, w.free)
+		chunk := (v >> (n - take)) & (1<<take - 1)
+		w.buf[len(w.buf)-1] |= byte(chunk << (w.free - take))
+		w.free -= take
+		n -= take
+	}
+}
+
+// writeBit writes a single bit.
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// bitReader reads values written by a bitWriter.
+type bitReader struct {
+	buf []byte
+	pos uint // position of the next bit
+}
+
+// readBits reads n bits into the low bits of the result.
+func (r *bitReader) readBits(n uint) (v uint64, err error) {
+	for n > 0 {
+		i := r.pos / 8
+		if i >= uint(len(r.buf)) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		avail := 8 - r.pos%8
+		take := min(n, avail)
+		chunk := uint64(r.buf[i]>>(avail-take)) & (1<<take - 1)
+		v = v<<take | chunk
+		r.pos += take
+		n -= take
+	}
+	return v, nil
+}
+
+// readBit reads a single bit.
+func (r *bitReader) readBit() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
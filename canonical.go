@@ -0,0 +1,73 @@
+package tinybin
+
+import (
+	"crypto/sha256"
+	"math"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Canonical enables the canonical encoding mode, pass it to New:
+// tb := tinybin.New(tinybin.Canonical{})
+//
+// In canonical mode every logical value has exactly one encoding: NaNs are
+// written as the canonical quiet NaN and negative zero as positive zero.
+// Varints are always written in their minimal form, nil and empty slices and
+// strings both encode as a zero length, and maps are not supported by tinybin
+// so there is no key order to settle. Fields tagged `binary:"encrypt"` use a
+// random nonce and are rejected.
+type Canonical struct{}
+
+// Hash returns the SHA-256 digest of the canonical encoding of a value, so two
+// equal values always produce the same digest. The digest covers the encoded
+// value only, not the header, compression or checksum of the instance, nor
+// the name of its type.
+func (tb *TinyBin) Hash(v any) (sum [sha256.Size]byte, err error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return sum, Errf("cannot encode nil value")
+	}
+
+	c, err := tb.scanToCache(rv.Type())
+	if err != nil {
+		return sum, err
+	}
+
+	h := sha256.New()
+	e := tb.encoders.Get().(*encoder)
+	e.Reset(h, tb)
+	e.canonical = true
+	if err = c.EncodeTo(e, rv); err == nil {
+		err = e.err
+	}
+	tb.encoders.Put(e)
+	if err != nil {
+		return sum, err
+	}
+
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// canonicalFloat32 maps every NaN to the quiet NaN and -0 to +0.
+func canonicalFloat32(v float32) float32 {
+	switch {
+	case v != v:
+		return math.Float32frombits(0x7FC00000)
+	case v == 0:
+		return 0
+	}
+	return v
+}
+
+// canonicalFloat64 maps every NaN to the quiet NaN and -0 to +0.
+func canonicalFloat64(v float64) float64 {
+	switch {
+	case v != v:
+		return math.Float64frombits(0x7FF8000000000000)
+	case v == 0:
+		return 0
+	}
+	return v
+}
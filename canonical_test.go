@@ -0,0 +1,88 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+type canonicalStruct struct {
+	F32    float32
+	F64    float64
+	Values []float64
+	Tags   []string
+	Data   []byte
+	Name   string
+}
+
+func TestCanonicalFloats(t *testing.T) {
+	tb := New(Canonical{})
+	a := canonicalStruct{
+		F32:    float32(math.Copysign(0, -1)),
+		F64:    math.Float64frombits(0x7FF0000000000001), // signalling NaN
+		Values: []float64{math.Float64frombits(0xFFF8000000000000)},
+	}
+	b := canonicalStruct{F32: 0, F64: math.NaN(), Values: []float64{math.NaN()}}
+
+	ea, err := tb.Encode(&a)
+	assertNoError(t, err)
+	eb, err := tb.Encode(&b)
+	assertNoError(t, err)
+	assertEqualBytes(t, eb, ea)
+
+	// Without the canonical mode the bit patterns are kept
+	pa, err := New().Encode(&a)
+	assertNoError(t, err)
+	if bytes.Equal(pa, ea) {
+		t.Error("Expected raw float bits without canonical mode")
+	}
+}
+
+func TestCanonicalNilAndEmpty(t *testing.T) {
+	tb := New(Canonical{})
+	ea, err := tb.Encode(&canonicalStruct{})
+	assertNoError(t, err)
+	eb, err := tb.Encode(&canonicalStruct{Values: []float64{}, Tags: []string{}, Data: []byte{}})
+	assertNoError(t, err)
+	assertEqualBytes(t, ea, eb)
+}
+
+func TestHash(t *testing.T) {
+	tb := New()
+	a := canonicalStruct{F64: math.Copysign(0, -1), Name: "x", Tags: []string{}}
+	b := canonicalStruct{F64: 0, Name: "x"}
+
+	ha, err := tb.Hash(&a)
+	assertNoError(t, err)
+	hb, err := tb.Hash(b)
+	assertNoError(t, err)
+	assertEqual(t, ha, hb)
+
+	b.Name = "y"
+	hc, err := tb.Hash(&b)
+	assertNoError(t, err)
+	if ha == hc {
+		t.Error("Expected different digests for different values")
+	}
+
+	// The digest ignores the framing of the instance
+	hd, err := New(ChecksumIEEE, Compression{Compressor: FlateCompressor{}}).Hash(&a)
+	assertNoError(t, err)
+	assertEqual(t, ha, hd)
+
+	// Hashing does not leak the canonical mode into the pooled encoders
+	raw, err := tb.Encode(&a)
+	assertNoError(t, err)
+	canonical, err := New(Canonical{}).Encode(&a)
+	assertNoError(t, err)
+	if bytes.Equal(raw, canonical) {
+		t.Error("Expected plain Encode to keep negative zero")
+	}
+}
+
+func TestHashEncrypted(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	if _, err := tb.Hash(&encryptedStruct{SSN: "secret"}); err == nil {
+		t.Fatal("Expected error hashing encrypted fields")
+	}
+}
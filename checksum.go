@@ -0,0 +1,125 @@
+package tinybin
+
+import (
+	"hash/crc32"
+	"io"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Checksum selects the integrity check appended to encoded payloads. Pass it
+// to New to enable it: tb := tinybin.New(tinybin.ChecksumCastagnoli)
+type Checksum uint8
+
+const (
+	ChecksumNone       Checksum = iota // No integrity trailer (default)
+	ChecksumIEEE                       // CRC-32 with the IEEE polynomial
+	ChecksumCastagnoli                 // CRC-32C with the Castagnoli polynomial
+)
+
+// ErrChecksum is returned by Decode when the integrity trailer does not match
+// the received bytes. No codec runs on a payload that fails verification.
+var ErrChecksum = Err(D.Binary, "checksum", D.Mismatch)
+
+// MaxMessageSize bounds the length of a payload read from a stream, which is
+// checked before any buffer is allocated. Pass it to New to change the default
+// of 16 MiB: tb := tinybin.New(tinybin.MaxMessageSize(1 << 20))
+type MaxMessageSize int
+
+// defaultMaxMessageSize is the limit of instances without MaxMessageSize.
+const defaultMaxMessageSize = 16 << 20
+
+// ErrMessageSize is returned when a payload read from a stream announces a
+// length above the MaxMessageSize of the instance.
+var ErrMessageSize = Err(D.Binary, "message", D.Exceeds, D.Maximum)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// writeChecked writes the body as a checksummed frame: the uvarint body length,
+// the body and the little-endian CRC of both.
+func (tb *TinyBin) writeChecked(body []byte, dst io.Writer) error {
+	e := tb.encoders.Get().(*encoder)
+	e.Reset(dst, tb)
+
+	n := putUvarint(e.scratch[:], uint64(len(body)))
+	crc := crc32.Update(0, tb.crcTable(), e.scratch[:n])
+	crc = crc32.Update(crc, tb.crcTable(), body)
+
+	e.Write(e.scratch[:n])
+	e.Write(body)
+	e.WriteUint32(crc)
+
+	err := e.err
+	tb.encoders.Put(e)
+	return err
+}
+
+// openChecked verifies a checksummed frame held in memory and returns its body.
+func (tb *TinyBin) openChecked(data []byte) ([]byte, error) {
+	r := newSliceReader(data)
+	l, err := r.ReadUvarint()
+	if err != nil || r.Len() < 4 || l != uint64(r.Len()-4) {
+		return nil, ErrChecksum
+	}
+
+	framed := data[:len(data)-4]
+	if crc32.Checksum(framed, tb.crcTable()) != readUint32(data[len(data)-4:]) {
+		return nil, ErrChecksum
+	}
+	return framed[int(r.offset):], nil
+}
+
+// readChecked reads a checksummed frame from a stream and returns its body.
+func (tb *TinyBin) readChecked(r reader) ([]byte, error) {
+	l, err := r.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > tb.messageLimit() {
+		return nil, ErrMessageSize
+	}
+
+	// The frame keeps the length prefix so the CRC covers it as well
+	var prefix [10]byte
+	n := putUvarint(prefix[:], l)
+	frame := make([]byte, n+int(l)+4)
+	copy(frame, prefix[:n])
+	if _, err = io.ReadFull(r, frame[n:]); err != nil {
+		return nil, err
+	}
+	return tb.openChecked(frame)
+}
+
+// messageLimit returns the largest payload length accepted from a stream.
+func (tb *TinyBin) messageLimit() uint64 {
+	if tb.maxMessage > 0 {
+		return uint64(tb.maxMessage)
+	}
+	return defaultMaxMessageSize
+}
+
+// crcTable returns the polynomial table of the configured checksum.
+func (tb *TinyBin) crcTable() *crc32.Table {
+	if tb.checksum == ChecksumCastagnoli {
+		return castagnoliTable
+	}
+	return crc32.IEEETable
+}
+
+// putUvarint encodes a uvarint into buf and returns the number of bytes written.
+func putUvarint(buf []byte, x uint64) int {
+	i := 0
+	for x >= 0x80 {
+		buf[i] = byte(x) | 0x80
+		x >>= 7
+		i++
+	}
+	buf[i] = byte(x)
+	return i + 1
+}
+
+// readUint32 reads a little-endian uint32.
+func readUint32(b []byte) uint32 {
+	_ = b[3] // bounds check hint to compiler
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
@@ -0,0 +1,97 @@
+package tinybin
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestChecksumRoundTrip(t *testing.T) {
+	for _, sum := range []Checksum{ChecksumIEEE, ChecksumCastagnoli} {
+		tb := New(sum)
+		in := FixtureBasic{Name: "sensor", Timestamp: 1700000000, Tags: []uint32{1, 2}, Score: 0.5}
+
+		data, err := tb.Encode(&in)
+		assertNoError(t, err)
+
+		var out FixtureBasic
+		assertNoError(t, tb.Decode(data, &out))
+		assertEqual(t, in, out)
+	}
+}
+
+func TestChecksumDetectsCorruption(t *testing.T) {
+	tb := New(ChecksumCastagnoli)
+	data, err := tb.Encode(&FixtureBasic{Name: "sensor", Count: 3})
+	assertNoError(t, err)
+
+	for i := range data {
+		corrupted := append([]byte(nil), data...)
+		corrupted[i] ^= 0x10
+
+		var out FixtureBasic
+		if err := tb.Decode(corrupted, &out); err != ErrChecksum {
+			t.Errorf("byte %d: expected ErrChecksum, got %v", i, err)
+		}
+	}
+
+	var out FixtureBasic
+	if err := tb.Decode(data[:len(data)-1], &out); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum for truncated payload, got %v", err)
+	}
+}
+
+func TestChecksumStream(t *testing.T) {
+	tb := New(ChecksumIEEE)
+
+	var stream bytes.Buffer
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "a", Age: 1}, &stream))
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "b", Age: 2}, &stream))
+
+	r := bufio.NewReader(&stream)
+	var first, second basicStruct
+	assertNoError(t, tb.DecodeFrom(r, &first))
+	assertNoError(t, tb.DecodeFrom(r, &second))
+	assertEqual(t, "a", first.Name)
+	assertEqual(t, 2, second.Age)
+
+	// Corrupt the body of a streamed payload
+	data, err := tb.Encode(&basicStruct{Name: "c"})
+	assertNoError(t, err)
+	data[2] ^= 0xFF
+	if err := tb.DecodeFrom(bytes.NewReader(data), &first); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+}
+
+func TestDecodeFromWithoutChecksum(t *testing.T) {
+	tb := New()
+	data, err := tb.Encode(&basicStruct{Name: "plain", Age: 7})
+	assertNoError(t, err)
+
+	var out basicStruct
+	assertNoError(t, tb.DecodeFrom(&oneByteReader{content: data}, &out))
+	assertEqual(t, "plain", out.Name)
+	assertEqual(t, 7, out.Age)
+}
+
+func TestChecksumStreamSizeLimit(t *testing.T) {
+	tb := New(ChecksumIEEE, MaxMessageSize(16))
+
+	var stream bytes.Buffer
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "a"}, &stream))
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "too long for the limit"}, &stream))
+
+	r := bufio.NewReader(&stream)
+	var out basicStruct
+	assertNoError(t, tb.DecodeFrom(r, &out))
+	if err := tb.DecodeFrom(r, &out); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize, got %v", err)
+	}
+
+	// A corrupted length is rejected before the frame is allocated
+	huge := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x07}
+	if err := New(ChecksumIEEE).DecodeFrom(bytes.NewReader(huge), &out); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize, got %v", err)
+	}
+}
@@ -283,6 +283,15 @@ type varintSliceCodec struct{}
 
 // Encode encodes a value into the encoder.
 func (c *varintSliceCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
+	if e.tb.fixedInts() {
+		encodeFixedSlice(e, rv, true)
+		return nil
+	}
+	if e.tb.adaptiveInts() {
+		encodeIntSlice(e, rv, true)
+		return nil
+	}
+
 	l := rv.Len()
 	e.WriteUvarint(uint64(l))
 	for i := 0; i < l; i++ {
@@ -295,6 +304,13 @@ func (c *varintSliceCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
 
 // Decode decodes into a reflect value from the decoder.
 func (c *varintSliceCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	if d.fixedInts() {
+		return decodeFixedSlice(d, rv, true)
+	}
+	if d.adaptiveInts() {
+		return decodeIntSlice(d, rv, true)
+	}
+
 	var l uint64
 	if l, err = d.ReadUvarint(); err == nil && l > 0 {
 		typ := rv.Type()
@@ -317,6 +333,15 @@ type varuintSliceCodec struct{}
 
 // Encode encodes a value into the encoder.
 func (c *varuintSliceCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
+	if e.tb.fixedInts() {
+		encodeFixedSlice(e, rv, false)
+		return nil
+	}
+	if e.tb.adaptiveInts() {
+		encodeIntSlice(e, rv, false)
+		return nil
+	}
+
 	l := rv.Len()
 	e.WriteUvarint(uint64(l))
 	for i := 0; i < l; i++ {
@@ -329,6 +354,13 @@ func (c *varuintSliceCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
 
 // Decode decodes into a reflect value from the decoder.
 func (c *varuintSliceCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	if d.fixedInts() {
+		return decodeFixedSlice(d, rv, false)
+	}
+	if d.adaptiveInts() {
+		return decodeIntSlice(d, rv, false)
+	}
+
 	var l, v uint64
 	if l, err = d.ReadUvarint(); err == nil && l > 0 {
 		typ := rv.Type()
@@ -394,14 +426,31 @@ func (c *reflectPointerCodec) DecodeTo(d *decoder, rv reflect.Value) (err error)
 type reflectStructCodec []fieldCodec
 
 type fieldCodec struct {
-	Index int   // The index of the field
+	Index int   // The index of the field, -1 for codecs reading the whole struct
 	Codec Codec // The codec to use for this field
 }
 
+// value returns the field of the struct handled by the codec.
+func (fc fieldCodec) value(rv reflect.Value) reflect.Value {
+	if fc.Index < 0 {
+		return rv
+	}
+	return rv.Field(fc.Index)
+}
+
+// field returns the index of the struct field handled by the codec, or -1 for
+// codecs reading the whole struct on behalf of several fields.
+func (fc fieldCodec) field() int {
+	if f, ok := fc.Codec.(interface{ fieldIndex() int }); ok {
+		return f.fieldIndex()
+	}
+	return fc.Index
+}
+
 // Encode encodes a value into the encoder.
 func (c reflectStructCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
 	for _, i := range c {
-		field := rv.Field(i.Index)
+		field := i.value(rv)
 		if err = i.Codec.EncodeTo(e, field); err != nil {
 			return err
 		}
@@ -412,7 +461,7 @@ func (c reflectStructCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
 // Decode decodes into a reflect value from the decoder.
 func (c reflectStructCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
 	for _, fieldCodec := range c {
-		v := rv.Field(fieldCodec.Index)
+		v := fieldCodec.value(rv)
 
 		// Debug: Check if codec is nil
 		if fieldCodec.Codec == nil {
@@ -445,12 +494,24 @@ type stringCodec struct{}
 // Encode encodes a value into the encoder.
 func (c *stringCodec) EncodeTo(e *encoder, rv reflect.Value) error {
 	s := rv.String()
+	if e.dict != nil {
+		e.writeDictString(s)
+		return nil
+	}
 	e.WriteString(s)
 	return nil
 }
 
 // Decode decodes into a reflect value from the decoder.
 func (c *stringCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	if d.dict != nil {
+		var s string
+		if s, err = d.readDictString(); err == nil {
+			rv.SetString(s)
+		}
+		return err
+	}
+
 	var s string
 	if s, err = d.ReadString(); err == nil {
 		rv.SetString(s)
@@ -484,6 +545,11 @@ type varintCodec struct{}
 
 // Encode encodes a value into the encoder.
 func (c *varintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	if e.tb.fixedInts() {
+		writeFixed(e, uint64(rv.Int()), fixedSize(Kind(rv.Kind())))
+		return nil
+	}
+
 	intVal := rv.Int()
 	e.WriteVarint(intVal)
 	return nil
@@ -491,6 +557,14 @@ func (c *varintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
 
 // Decode decodes into a reflect value from the decoder.
 func (c *varintCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	if d.fixedInts() {
+		var bits uint64
+		if bits, err = readFixed(d, fixedSize(Kind(rv.Kind())), true); err == nil {
+			rv.SetInt(int64(bits))
+		}
+		return err
+	}
+
 	var v int64
 	if v, err = d.ReadVarint(); err != nil {
 		return err
@@ -505,6 +579,11 @@ type varuintCodec struct{}
 
 // Encode encodes a value into the encoder.
 func (c *varuintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	if e.tb.fixedInts() {
+		writeFixed(e, rv.Uint(), fixedSize(Kind(rv.Kind())))
+		return nil
+	}
+
 	uintVal := rv.Uint()
 	e.WriteUvarint(uintVal)
 	return nil
@@ -512,6 +591,14 @@ func (c *varuintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
 
 // Decode decodes into a reflect value from the decoder.
 func (c *varuintCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	if d.fixedInts() {
+		var bits uint64
+		if bits, err = readFixed(d, fixedSize(Kind(rv.Kind())), false); err == nil {
+			rv.SetUint(bits)
+		}
+		return err
+	}
+
 	var v uint64
 	if v, err = d.ReadUvarint(); err != nil {
 		return err
@@ -0,0 +1,68 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// columnarCodec encodes a slice of structs field by field, as tagged with
+// `binary:"columnar"`: the uvarint length of the slice followed by one column
+// per field holding the value of that field in every element. A group of
+// bitfields forms a single column.
+type columnarCodec struct {
+	fields reflectStructCodec // The codecs of the element fields
+}
+
+// newColumnarCodec returns the columnar codec of a slice of structs.
+func newColumnarCodec(t reflect.Type) (Codec, error) {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Struct {
+		return nil, Err("columnar", D.Type, t.String(), D.Not, D.Supported)
+	}
+
+	c, err := scanType(t.Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns are not aligned, so the element padding is dropped
+	var fields reflectStructCodec
+	for _, field := range *c.(*reflectStructCodec) {
+		if _, ok := field.Codec.(padCodec); !ok {
+			fields = append(fields, field)
+		}
+	}
+	return &columnarCodec{fields: fields}, nil
+}
+
+// Encode encodes a value into the encoder.
+func (c *columnarCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
+	l := rv.Len()
+	e.WriteUvarint(uint64(l))
+	for _, field := range c.fields {
+		for i := 0; i < l; i++ {
+			if err = field.Codec.EncodeTo(e, field.value(rv.Index(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *columnarCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	var l uint64
+	if l, err = d.ReadUvarint(); err != nil || l == 0 {
+		return err
+	}
+
+	rv.Set(reflect.MakeSlice(rv.Type(), int(l), int(l)))
+	for _, field := range c.fields {
+		for i := 0; i < int(l); i++ {
+			if err = field.Codec.DecodeTo(d, field.value(rv.Index(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
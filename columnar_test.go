@@ -0,0 +1,130 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type reading struct {
+	Time     int64
+	SensorID uint16
+	Value    float32
+	Label    *string
+}
+
+type readingBatch struct {
+	Device   string
+	Readings []reading `binary:"columnar"`
+}
+
+func newReadingBatch(n int) readingBatch {
+	label := "calibrated"
+	b := readingBatch{Device: "gw-01"}
+	for i := 0; i < n; i++ {
+		r := reading{Time: 1700000000 + int64(i), SensorID: uint16(i % 3), Value: float32(i) / 2}
+		if i%2 == 0 {
+			r.Label = &label
+		}
+		b.Readings = append(b.Readings, r)
+	}
+	return b
+}
+
+func TestColumnarRoundTrip(t *testing.T) {
+	tb := New()
+	in := newReadingBatch(5)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var out readingBatch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// The time column comes first: five consecutive varints right after the length
+	prefix := New()
+	var times bytes.Buffer
+	for _, r := range in.Readings {
+		b, err := prefix.Encode(r.Time)
+		assertNoError(t, err)
+		times.Write(b)
+	}
+	if !bytes.Contains(data, times.Bytes()) {
+		t.Error("Expected the time column to be written contiguously")
+	}
+}
+
+func TestColumnarEmpty(t *testing.T) {
+	tb := New()
+	data, err := tb.Encode(&readingBatch{Device: "x"})
+	assertNoError(t, err)
+
+	var out readingBatch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, readingBatch{Device: "x"}, out)
+}
+
+func TestColumnarInvalidType(t *testing.T) {
+	type invalid struct {
+		Values []int `binary:"columnar"`
+	}
+	if _, err := New().Encode(&invalid{}); err == nil {
+		t.Fatal("Expected error for columnar slice of non-structs")
+	}
+}
+
+func TestColumnarSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(readingBatch{})
+	assertNoError(t, err)
+	assertEqual(t, wireColumnar, schema.Fields[1].wire())
+
+	data, err := tb.Encode(&readingBatch{Device: "gw", Readings: newReadingBatch(4).Readings})
+	assertNoError(t, err)
+
+	// Skipped by readers without the field
+	var device struct{ Device string }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &device))
+	assertEqual(t, "gw", device.Device)
+
+	// Row-wise readers can not reinterpret the columns
+	var rows struct {
+		Device   string
+		Readings []reading
+	}
+	if err := tb.DecodeWithWriterSchema(data, schema, &rows); err == nil {
+		t.Error("Expected error resolving columnar field into a row-wise slice")
+	}
+}
+
+func TestColumnarContainerValues(t *testing.T) {
+	tb := New()
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, readingBatch{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&readingBatch{Device: "a", Readings: newReadingBatch(3).Readings}))
+	assertNoError(t, w.Close())
+
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+
+	readings, ok := v.Field("Readings")
+	assertEqual(t, true, ok)
+	assertEqualInt(t, 3, len(readings.Items))
+	sensor, ok := readings.Items[2].Field("SensorID")
+	assertEqual(t, true, ok)
+	assertEqual(t, uint64(2), sensor.Scalar)
+	// A corrupted row count fails instead of allocating every row
+	schema, err := tb.Schema(readingBatch{})
+	assertNoError(t, err)
+	for _, huge := range [][]byte{
+		{1, 'a', 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F, 2},
+		{1, 'a', 0xFF, 0xFF, 0xFF, 0x7F, 2},
+	} {
+		if _, err := decodeValue(&decoder{reader: newSliceReader(huge), tb: tb}, &schema); err == nil {
+			t.Error("Expected error for a corrupted row count")
+		}
+	}
+}
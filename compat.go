@@ -0,0 +1,145 @@
+package tinybin
+
+import (
+	. "github.com/cdvelop/tinystring"
+)
+
+// Incompatibility describes a change between two schemas that prevents
+// payloads written with the old schema from being decoded with the new one.
+type Incompatibility struct {
+	Path   string // Dotted path to the affected field, "[]" marks elements
+	Reason string // Human readable description of the change
+	Old    string // Go type in the old schema, empty if the field was added
+	New    string // Go type in the new schema, empty if the field was removed
+}
+
+// String returns a single line description of the incompatibility.
+func (i Incompatibility) String() string {
+	path := i.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return Fmt("%s: %s (%s -> %s)", path, i.Reason, i.Old, i.New)
+}
+
+// CheckCompatibility reports the breaking changes between two schemas of the
+// same message: removed, added or reordered positional fields, type changes
+// that alter the wire representation and changed pointer nullability. Type
+// changes that keep the representation, such as int32 to int64 or []byte to
+// string, are not reported. An empty result means old payloads still decode.
+func CheckCompatibility(old, new Schema) []Incompatibility {
+	var out []Incompatibility
+	checkCompatibility(&old, &new, "", &out)
+	return out
+}
+
+func checkCompatibility(old, cur *Schema, path string, out *[]Incompatibility) {
+	report := func(reason string) {
+		*out = append(*out, Incompatibility{Path: path, Reason: reason, Old: old.Type, New: cur.Type})
+	}
+
+	ow, cw := old.wire(), cur.wire()
+	if (ow == wirePointer) != (cw == wirePointer) {
+		report("pointer nullability changed")
+
+		// Keep comparing the pointed type to catch further changes
+		if ow == wirePointer {
+			old = old.Elem
+		} else {
+			cur = cur.Elem
+		}
+		ow, cw = old.wire(), cur.wire()
+	}
+
+	if ow != cw {
+		report("wire type changed")
+		return
+	}
+	if old.byteOrder() != cur.byteOrder() {
+		report("byte order changed")
+	}
+	if tagOptions(old.Tag).Has("encrypt") != tagOptions(cur.Tag).Has("encrypt") {
+		report("encryption changed")
+	}
+	if oc, _ := old.constant(); oc != "" {
+		if cc, _ := cur.constant(); cc != "" && !sameConstant(oc, cc) {
+			report("constant changed")
+		}
+	}
+	oc, ook := tagOptions(old.Tag).Get("if")
+	if cc, cok := tagOptions(cur.Tag).Get("if"); ook != cok || oc != cc {
+		report("condition changed")
+	}
+
+	switch ow {
+	case wirePointer:
+		checkCompatibility(old.Elem, cur.Elem, path, out)
+	case wireSlice, wireColumnar, wireDelta, wireXORFloat:
+		checkCompatibility(old.Elem, cur.Elem, path+"[]", out)
+	case wireBits:
+		if old.bitWidth() != cur.bitWidth() {
+			report("bit width changed")
+		}
+	case wireCounted:
+		if old.countField() != cur.countField() {
+			report("length field changed")
+		}
+		if old.Elem != nil && cur.Elem != nil {
+			checkCompatibility(old.Elem, cur.Elem, path+"[]", out)
+		}
+	case wireSized:
+		if old.byteSize() != cur.byteSize() {
+			report("size changed")
+		}
+	case wireFixed:
+		if old.fixedWidth() != cur.fixedWidth() {
+			report("fixed width changed")
+		}
+	case wireArray:
+		if old.Len != cur.Len {
+			report("array length changed")
+			return
+		}
+		checkCompatibility(old.Elem, cur.Elem, path+"[]", out)
+	case wireStruct:
+		checkFields(old, cur, path, out)
+	}
+}
+
+// checkFields matches struct fields by name and compares their positions and types.
+func checkFields(old, cur *Schema, path string, out *[]Incompatibility) {
+	last := -1
+	for i := range old.Fields {
+		of := &old.Fields[i]
+		fieldPath := joinPath(path, of.Name)
+		j := cur.field(of.Name)
+		if j < 0 {
+			*out = append(*out, Incompatibility{Path: fieldPath, Reason: "field removed", Old: of.Type})
+			continue
+		}
+
+		// Only a change in the relative order of surviving fields is a reorder,
+		// positions shifted by a removal are already reported above.
+		if j < last {
+			*out = append(*out, Incompatibility{Path: fieldPath, Reason: "field reordered", Old: of.Type, New: cur.Fields[j].Type})
+		} else {
+			last = j
+		}
+
+		checkCompatibility(of, &cur.Fields[j], fieldPath, out)
+	}
+
+	for j := range cur.Fields {
+		nf := &cur.Fields[j]
+		if old.field(nf.Name) < 0 {
+			*out = append(*out, Incompatibility{Path: joinPath(path, nf.Name), Reason: "field added", New: nf.Type})
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
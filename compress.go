@@ -0,0 +1,85 @@
+package tinybin
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// Compressor compresses encoded payloads. Implementations must be safe for
+// concurrent use, as a single instance is shared by all encoders and decoders.
+type Compressor interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// Compression enables payload compression, pass it to New:
+// tb := tinybin.New(tinybin.Compression{Compressor: tinybin.FlateCompressor{}, Threshold: 256})
+type Compression struct {
+	Compressor Compressor // Algorithm used for the payloads
+	Threshold  int        // Payloads smaller than this many bytes are stored uncompressed
+}
+
+// FlateCompressor implements Compressor with compress/flate.
+type FlateCompressor struct {
+	Level int // flate compression level, 0 selects flate.DefaultCompression
+}
+
+// Compress implements Compressor.
+func (c FlateCompressor) Compress(src []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	var buffer bytes.Buffer
+	w, err := flate.NewWriter(&buffer, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(src); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decompress implements Compressor. Payloads inflating past 16 MiB, the
+// default MaxMessageSize, fail with ErrMessageSize; instances decompress up to
+// their own MaxMessageSize.
+func (c FlateCompressor) Decompress(src []byte) ([]byte, error) {
+	return c.decompress(src, defaultMaxMessageSize)
+}
+
+// decompress inflates src, failing with ErrMessageSize past limit bytes.
+func (c FlateCompressor) decompress(src []byte, limit uint64) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	raw, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err == nil && uint64(len(raw)) > limit {
+		return nil, ErrMessageSize
+	}
+	return raw, err
+}
+
+// limitedDecompressor is implemented by compressors able to stop inflating a
+// payload once it exceeds the MaxMessageSize of the instance.
+type limitedDecompressor interface {
+	decompress(src []byte, limit uint64) ([]byte, error)
+}
+
+// compress returns the compressed payload when compression is enabled, the
+// payload reaches the threshold and compressing actually makes it smaller.
+func (c *Compression) compress(payload []byte) ([]byte, bool, error) {
+	if c.Compressor == nil || len(payload) < c.Threshold {
+		return payload, false, nil
+	}
+
+	compressed, err := c.Compressor.Compress(payload)
+	if err != nil || len(compressed) >= len(payload) {
+		return payload, false, err
+	}
+	return compressed, true, nil
+}
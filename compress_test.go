@@ -0,0 +1,93 @@
+package tinybin
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	tb := New(Compression{Compressor: FlateCompressor{}, Threshold: 64})
+	in := FixtureBasic{Name: strings.Repeat("level=info host=gw-01 ", 50), Tags: make([]uint32, 200)}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	if data[0]&headerCompressed == 0 {
+		t.Fatal("Expected compressed payload")
+	}
+
+	plain, err := New().Encode(&in)
+	assertNoError(t, err)
+	if len(data) >= len(plain) {
+		t.Errorf("Expected compressed payload (%d bytes) to be smaller than plain (%d bytes)", len(data), len(plain))
+	}
+
+	var out FixtureBasic
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	tb := New(Compression{Compressor: FlateCompressor{}, Threshold: 1024})
+
+	data, err := tb.Encode(&basicStruct{Name: "small"})
+	assertNoError(t, err)
+	assertEqual(t, byte(0), data[0])
+
+	var out basicStruct
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, "small", out.Name)
+}
+
+func TestCompressionStream(t *testing.T) {
+	tb := New(Compression{Compressor: FlateCompressor{Level: 9}}, ChecksumIEEE)
+	long := strings.Repeat("abc", 100)
+
+	var stream bytes.Buffer
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: long, Age: 1}, &stream))
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "short", Age: 2}, &stream))
+
+	r := bufio.NewReader(&stream)
+	var first, second basicStruct
+	assertNoError(t, tb.DecodeFrom(r, &first))
+	assertNoError(t, tb.DecodeFrom(r, &second))
+	assertEqual(t, long, first.Name)
+	assertEqual(t, "short", second.Name)
+}
+
+func TestCompressionWithVersions(t *testing.T) {
+	tb := New(Compression{Compressor: FlateCompressor{}})
+	assertNoError(t, tb.RegisterVersion(1, basicStruct{}))
+
+	in := basicStruct{Name: strings.Repeat("z", 500), Age: 3}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqual(t, headerVersion|headerCompressed, data[0])
+
+	var out basicStruct
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestCompressionSizeLimit(t *testing.T) {
+	in := basicStruct{Name: strings.Repeat("z", 100<<10)}
+	data, err := New(Compression{Compressor: FlateCompressor{}}).Encode(&in)
+	assertNoError(t, err)
+
+	// The payload inflates past the limit of the decoder
+	var out basicStruct
+	if err := New(Compression{Compressor: FlateCompressor{}}, MaxMessageSize(1024)).Decode(data, &out); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize, got %v", err)
+	}
+
+	// A corrupted compressed length
+	tb := New(Compression{Compressor: FlateCompressor{}})
+	corrupted := []byte{headerCompressed, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}
+	if err := tb.Decode(corrupted, &out); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize, got %v", err)
+	}
+	if err := tb.DecodeFrom(bytes.NewReader(corrupted), &out); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize from a stream, got %v", err)
+	}
+}
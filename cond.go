@@ -0,0 +1,250 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// condition is the test of a field tagged with `binary:"if=Kind==3"`: the field
+// is only written when the earlier field Kind holds 3. `if=Kind!=3` inverts the
+// test and `if=Extended` requires the bool field Extended to be true.
+type condition struct {
+	field   string // The name of the field tested
+	literal string // The value compared, "true" or "false" for bool fields
+	value   uint64 // The value compared, as parsed by parseConst, 1 for true
+	not     bool   // The field must differ from the value
+}
+
+// parseCondition parses the expression of an `if` tag option.
+func parseCondition(expr string) (condition, bool) {
+	c := condition{field: expr, literal: "true", value: 1}
+	if i := Index(expr, "!="); i >= 0 {
+		c = condition{field: expr[:i], literal: expr[i+2:], not: true}
+	} else if i := Index(expr, "=="); i >= 0 {
+		c = condition{field: expr[:i], literal: expr[i+2:]}
+	}
+	if c.field == "" {
+		return c, false
+	}
+
+	switch c.literal {
+	case "true":
+		c.value = 1
+	case "false":
+		c.value = 0
+	default:
+		var ok bool
+		if c.value, ok = parseConst(c.literal); !ok {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+// flag reports whether the condition tests a bool field.
+func (c condition) flag() bool {
+	return c.literal == "true" || c.literal == "false"
+}
+
+// accepts reports whether the condition can test a field of type t.
+func (c condition) accepts(t reflect.Type) bool {
+	switch k := Kind(t.Kind()); {
+	case k == K.Bool:
+		return c.flag()
+	case signedKind(k):
+		return !c.flag()
+	case unsignedKind(k):
+		return !c.flag() && c.literal[0] != '-'
+	}
+	return false
+}
+
+// holds evaluates the condition on the value of the field tested.
+func (c condition) holds(rv reflect.Value) bool {
+	switch {
+	case rv.Kind() == reflect.Bool:
+		return c.holdsScalar(rv.Bool())
+	case rv.CanInt():
+		return c.holdsScalar(rv.Int())
+	}
+	return c.holdsScalar(rv.Uint())
+}
+
+// holdsScalar evaluates the condition on a bool, int64 or uint64 decoded
+// without a Go type.
+func (c condition) holdsScalar(scalar any) bool {
+	var equal bool
+	switch x := scalar.(type) {
+	case bool:
+		equal = c.flag() && x == (c.value == 1)
+	case int64:
+		equal = !c.flag() && uint64(x) == c.value && (x < 0) == (c.literal[0] == '-')
+	case uint64:
+		equal = !c.flag() && x == c.value && c.literal[0] != '-'
+	}
+	return equal != c.not
+}
+
+// ------------------------------------------------------------------------------
+
+// conditionalCodec encodes a field tagged with `binary:"if=..."` only when its
+// condition holds on an earlier field, and zeroes it on decode otherwise. It
+// reads the whole struct, so its fieldCodec has an Index of -1.
+type conditionalCodec struct {
+	field  fieldCodec        // The codec of the field
+	cond   int               // The index of the field tested
+	parent *conditionalCodec // The codec of the field tested when conditional too
+	condition
+}
+
+func (c *conditionalCodec) fieldIndex() int {
+	return c.field.field()
+}
+
+// present reports whether the field is written in the struct rv. A tested field
+// left out by its own condition is tested as zero, the value decoders give it.
+func (c *conditionalCodec) present(rv reflect.Value) bool {
+	tested := rv.Field(c.cond)
+	if c.parent != nil && !c.parent.present(rv) {
+		tested = reflect.Zero(tested.Type())
+	}
+	return c.holds(tested)
+}
+
+// Encode encodes a value into the encoder.
+func (c *conditionalCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	if !c.present(rv) {
+		return nil
+	}
+	return c.field.Codec.EncodeTo(e, c.field.value(rv))
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *conditionalCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if c.present(rv) {
+		return c.field.Codec.DecodeTo(d, c.field.value(rv))
+	}
+	if i := c.field.field(); i >= 0 {
+		rv.Field(i).SetZero()
+	}
+	return nil
+}
+
+// linkConditions wraps the conditional fields of a struct in a codec testing
+// their condition field, which must be an earlier bool or integer field. Length
+// fields can neither be conditional nor tested, their value being the length
+// of the fields they count rather than the one of the struct.
+func linkConditions(t reflect.Type, fields reflectStructCodec) error {
+	for i := range fields {
+		index := fields[i].field()
+		if index < 0 {
+			continue
+		}
+		field := t.Field(index)
+		expr, ok := tagOptions(field.Tag.Get("binary")).Get("if")
+		if !ok {
+			continue
+		}
+
+		c, ok := parseCondition(expr)
+		if !ok {
+			return Err("if", field.Name, expr, D.Invalid)
+		}
+		if _, ok := fields[i].Codec.(*lengthCodec); ok {
+			return Err("if", field.Name, "len", D.Not, D.Supported)
+		}
+
+		cond := -1
+		var parent *conditionalCodec
+		for j := 0; j < i; j++ {
+			if group, ok := fields[j].Codec.(bitGroupCodec); ok {
+				for _, f := range group {
+					if t.Field(f.Index).Name == c.field {
+						cond = f.Index
+					}
+				}
+			} else if _, ok := fields[j].Codec.(*lengthCodec); !ok && fields[j].field() >= 0 && t.Field(fields[j].field()).Name == c.field {
+				cond = fields[j].field()
+				parent, _ = fields[j].Codec.(*conditionalCodec)
+			}
+		}
+		if cond < 0 {
+			return Err("if", field.Name, D.Field, c.field, D.Not, D.Found)
+		}
+		if !c.accepts(t.Field(cond).Type) {
+			return Err("if", field.Name, D.Type, t.Field(cond).Type.String(), D.Not, D.Supported)
+		}
+		codec := &conditionalCodec{field: fields[i], cond: cond, parent: parent, condition: c}
+		fields[i] = fieldCodec{Index: -1, Codec: codec}
+
+		// A counted field left out has a length of 0
+		for j := range fields {
+			if length, ok := fields[j].Codec.(*lengthCodec); ok {
+				for k, counted := range length.counted {
+					if counted == index {
+						length.conds[k] = codec
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// condition returns the condition of a field tagged with `binary:"if=..."`.
+func (s *Schema) condition() (condition, bool) {
+	expr, ok := tagOptions(s.Tag).Get("if")
+	if !ok {
+		return condition{}, false
+	}
+	return parseCondition(expr)
+}
+
+// present reports whether field i of a struct was written, evaluating its
+// condition on the earlier fields already decoded into items. A tested field
+// left out by its own condition holds the zero value, as typed decoders zero it.
+func present(fields []Schema, i int, items []Value) bool {
+	c, ok := fields[i].condition()
+	if !ok {
+		return true
+	}
+	for j := 0; j < i; j++ {
+		if fields[j].Name != c.field {
+			continue
+		}
+		scalar := items[j].Scalar
+		if scalar == nil {
+			switch k := fields[j].Kind; {
+			case k == K.Bool:
+				scalar = false
+			case signedKind(k):
+				scalar = int64(0)
+			default:
+				scalar = uint64(0)
+			}
+		}
+		return c.holdsScalar(scalar)
+	}
+	return false
+}
+
+// resolveConditional resolves a conditional field of the writer as the
+// untagged field, read when the condition holds on the reader's copy of the
+// field tested.
+func (tb *TinyBin) resolveConditional(owner string, w *Schema, c condition, t reflect.Type, readerFields *reflectStructCodec) (fieldCodec, error) {
+	plain := *w
+	plain.Tag = tagOptions(w.Tag).without("if")
+	fc, err := tb.resolveField(owner, &plain, t, readerFields)
+	if err != nil {
+		return fc, err
+	}
+
+	field, ok := t.FieldByName(c.field)
+	if !ok || len(field.Index) != 1 || field.Tag.Get("binary") == "-" || !c.accepts(field.Type) {
+		return fc, Err("if", w.Name, D.Field, c.field, D.Not, D.Found)
+	}
+	return fieldCodec{Index: -1, Codec: &conditionalCodec{field: fc, cond: field.Index[0], condition: c}}, nil
+}
@@ -0,0 +1,218 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type gatewayMsg struct {
+	Kind     uint8
+	Flags    uint8  `binary:"bits=3"`
+	Urgent   bool   `binary:"bits=1"`
+	Reading  int32  `binary:"if=Kind==3"`
+	Text     string `binary:"if=Kind!=3"`
+	Deadline uint16 `binary:"if=Urgent,fixed"`
+}
+
+func TestConditionalTagLayout(t *testing.T) {
+	tb := New()
+
+	data, err := tb.Encode(&gatewayMsg{Kind: 3, Flags: 1, Urgent: true, Reading: -2, Text: "dropped", Deadline: 0x0102})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{3, 0x30, 3, 0x02, 0x01}, data)
+
+	out := gatewayMsg{Text: "stale"}
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, gatewayMsg{Kind: 3, Flags: 1, Urgent: true, Reading: -2, Deadline: 0x0102}, out)
+
+	data, err = tb.Encode(&gatewayMsg{Kind: 1, Flags: 1, Reading: 7, Text: "ok", Deadline: 9})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 0x20, 2, 'o', 'k'}, data)
+
+	out = gatewayMsg{Reading: 5, Deadline: 5}
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, gatewayMsg{Kind: 1, Flags: 1, Text: "ok"}, out)
+
+	// Conditional fields of columnar rows test the column written before them
+	var table struct {
+		Rows []gatewayMsg `binary:"columnar"`
+	}
+	table.Rows = []gatewayMsg{{Kind: 3, Reading: 4}, {Kind: 2, Text: "x"}}
+	data, err = tb.Encode(&table)
+	assertNoError(t, err)
+	table.Rows = nil
+	assertNoError(t, tb.Decode(data, &table))
+	assertEqual(t, []gatewayMsg{{Kind: 3, Reading: 4}, {Kind: 2, Text: "x"}}, table.Rows)
+}
+
+func TestConditionalTagInvalid(t *testing.T) {
+	tb := New()
+	for _, in := range []any{
+		&struct {
+			V    uint8 `binary:"if=Kind==1"`
+			Kind uint8
+		}{},
+		&struct {
+			V uint8 `binary:"if=Kind==1"`
+		}{},
+		&struct {
+			Kind string
+			V    uint8 `binary:"if=Kind==1"`
+		}{},
+		&struct {
+			Kind uint8
+			V    uint8 `binary:"if=Kind==true"`
+		}{},
+		&struct {
+			Kind uint8
+			V    uint8 `binary:"if=Kind==-1"`
+		}{},
+		&struct {
+			On bool
+			V  uint8 `binary:"if=On==1"`
+		}{},
+		&struct {
+			Kind uint8
+			V    uint8 `binary:"if=Kind==0xZZ"`
+		}{},
+		&struct {
+			Kind uint8
+			V    uint8 `binary:"bits=4,if=Kind==1"`
+		}{},
+		&struct {
+			N    uint8 `binary:"if=Kind==1"`
+			Kind uint8
+			Data []byte `binary:"len=N"`
+		}{},
+		&struct {
+			N    uint8
+			Data []byte `binary:"len=N"`
+			V    uint8  `binary:"if=N==1"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for invalid condition in %T", in)
+		}
+	}
+
+	var signed struct {
+		Delta int8
+		V     uint8 `binary:"if=Delta==-1"`
+	}
+	signed.Delta, signed.V = -1, 4
+	data, err := tb.Encode(&signed)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 4}, data)
+}
+
+func TestConditionalTagSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(gatewayMsg{})
+	assertNoError(t, err)
+
+	reading, err := tb.Encode(&gatewayMsg{Kind: 3, Reading: 8})
+	assertNoError(t, err)
+	text, err := tb.Encode(&gatewayMsg{Kind: 4, Urgent: true, Text: "hi", Deadline: 1})
+	assertNoError(t, err)
+
+	// Readers may drop the tag and widen the field, the condition is still tested
+	var plain struct {
+		Kind    uint16
+		Urgent  bool
+		Reading int64
+		Text    string
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(reading, schema, &plain))
+	assertEqual(t, int64(8), plain.Reading)
+	assertEqual(t, "", plain.Text)
+	assertNoError(t, tb.DecodeWithWriterSchema(text, schema, &plain))
+	assertEqual(t, int64(0), plain.Reading)
+	assertEqual(t, "hi", plain.Text)
+
+	// Skipped fields need the reader to keep the fields they test
+	var kind struct{ Kind uint8 }
+	if err := tb.DecodeWithWriterSchema(text, schema, &kind); err == nil {
+		t.Error("Expected error resolving a condition on a missing field")
+	}
+	var nested struct {
+		Msg  gatewayMsg
+		Tail uint8
+	}
+	nested.Msg, nested.Tail = gatewayMsg{Kind: 4, Urgent: true, Text: "hi", Deadline: 1}, 9
+	data, err := tb.Encode(&nested)
+	assertNoError(t, err)
+	outer, err := tb.Schema(nested)
+	assertNoError(t, err)
+	var tail struct{ Tail uint8 }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, outer, &tail))
+	assertEqual(t, uint8(9), tail.Tail)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, gatewayMsg{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&gatewayMsg{Kind: 3, Reading: 8}))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	value, _ := v.Field("Reading")
+	assertEqual(t, int64(8), value.Scalar)
+	value, _ = v.Field("Text")
+	assertEqual(t, true, value.Nil)
+
+	// A tested field left out by its own condition is tested as zero
+	type chained struct {
+		Kind uint8
+		Sub  uint8 `binary:"if=Kind==1"`
+		V    uint8 `binary:"if=Sub!=0"`
+		Tail uint8
+	}
+	data, err = tb.Encode(&chained{Kind: 2, Sub: 5, V: 6, Tail: 7})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{2, 7}, data)
+	var chain chained
+	assertNoError(t, tb.Decode(data, &chain))
+	assertEqual(t, chained{Kind: 2, Tail: 7}, chain)
+
+	file.Reset()
+	w, err = tb.NewContainerWriter(&file, chained{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&chained{Kind: 2, Tail: 7}))
+	assertNoError(t, w.Close())
+	r, err = tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err = r.NextValue()
+	assertNoError(t, err)
+	value, _ = v.Field("V")
+	assertEqual(t, true, value.Nil)
+	value, _ = v.Field("Tail")
+	assertEqual(t, uint64(7), value.Scalar)
+
+	var outerChain struct {
+		Msg  chained
+		Tail uint8
+	}
+	outerChain.Msg, outerChain.Tail = chained{Kind: 2, Tail: 7}, 9
+	data, err = tb.Encode(&outerChain)
+	assertNoError(t, err)
+	outer, err = tb.Schema(outerChain)
+	assertNoError(t, err)
+	tail.Tail = 0
+	assertNoError(t, tb.DecodeWithWriterSchema(data, outer, &tail))
+	assertEqual(t, uint8(9), tail.Tail)
+
+	old, err := tb.Schema(struct {
+		Kind uint8
+		V    uint8 `binary:"if=Kind==1"`
+	}{})
+	assertNoError(t, err)
+	cur, err := tb.Schema(struct {
+		Kind uint8
+		V    uint8 `binary:"if=Kind==2"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "condition changed", issues[0].Reason)
+}
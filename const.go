@@ -0,0 +1,160 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// constCodec encodes an integer field tagged with `binary:"const=0xCAFE"`: the
+// encoder writes the constant whatever the value of the field, and the decoder
+// fails when it reads anything else, so foreign or misaligned frames are
+// rejected before the fields after it.
+type constCodec struct {
+	elemCodec Codec         // The codec of the field
+	value     reflect.Value // The constant, of the type of the field
+	name      string        // The name of the field
+	literal   string        // The constant as written in the tag
+}
+
+// newConstCodec returns the codec writing the constant of a field of type t
+// through the codec of the field.
+func newConstCodec(t reflect.Type, elemCodec Codec, name, literal string) (Codec, error) {
+	bits, ok := parseConst(literal)
+	if !ok {
+		return nil, Err("const", name, literal, D.Invalid)
+	}
+
+	value := reflect.New(t).Elem()
+	switch k := Kind(t.Kind()); {
+	case signedKind(k):
+		if value.OverflowInt(int64(bits)) || literal[0] != '-' && int64(bits) < 0 {
+			return nil, Errf("constant %s overflows field %s of type %s", literal, name, t.String())
+		}
+		value.SetInt(int64(bits))
+	case unsignedKind(k):
+		if literal[0] == '-' || value.OverflowUint(bits) {
+			return nil, Errf("constant %s overflows field %s of type %s", literal, name, t.String())
+		}
+		value.SetUint(bits)
+	default:
+		return nil, Err("const", D.Type, t.String(), D.Not, D.Supported)
+	}
+	return &constCodec{elemCodec: elemCodec, value: value, name: name, literal: literal}, nil
+}
+
+// Encode encodes a value into the encoder.
+func (c *constCodec) EncodeTo(e *encoder, _ reflect.Value) error {
+	return c.elemCodec.EncodeTo(e, c.value)
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *constCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if err := c.elemCodec.DecodeTo(d, rv); err != nil {
+		return err
+	}
+	switch {
+	case rv.CanInt() && rv.Int() != c.value.Int():
+		return constMismatch(c.name, rv.Int(), c.literal)
+	case rv.CanUint() && rv.Uint() != c.value.Uint():
+		return constMismatch(c.name, rv.Uint(), c.literal)
+	}
+	return nil
+}
+
+// constMismatch reports a decoded int64 or uint64 differing from the constant.
+func constMismatch(name string, got any, literal string) error {
+	return Errf("field %s holds %d instead of the constant %s", name, got, literal)
+}
+
+// parseConst parses a decimal or 0x prefixed hexadecimal constant, optionally
+// negative, into two's complement bits.
+func parseConst(s string) (uint64, bool) {
+	neg := len(s) > 0 && s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+
+	base := uint64(10)
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		base, s = 16, s[2:]
+	}
+	if s == "" {
+		return 0, false
+	}
+
+	var n uint64
+	for _, c := range s {
+		var digit uint64
+		switch {
+		case c >= '0' && c <= '9':
+			digit = uint64(c - '0')
+		case base == 16 && c >= 'a' && c <= 'f':
+			digit = uint64(c-'a') + 10
+		case base == 16 && c >= 'A' && c <= 'F':
+			digit = uint64(c-'A') + 10
+		default:
+			return 0, false
+		}
+		if n > (1<<64-1-digit)/base {
+			return 0, false
+		}
+		n = n*base + digit
+	}
+
+	if neg {
+		if n > 1<<63 {
+			return 0, false
+		}
+		n = -n
+	}
+	return n, true
+}
+
+// ------------------------------------------------------------------------------
+
+// constant returns the literal of a field tagged with `binary:"const=..."`.
+func (s *Schema) constant() (string, bool) {
+	return tagOptions(s.Tag).Get("const")
+}
+
+// checkConstant checks a value decoded without a Go type against the constant
+// of its field, if any.
+func (s *Schema) checkConstant(scalar any) error {
+	literal, ok := s.constant()
+	if !ok {
+		return nil
+	}
+
+	bits, _ := parseConst(literal)
+	switch x := scalar.(type) {
+	case int64:
+		ok = x == int64(bits) && (literal[0] == '-' || x >= 0)
+	case uint64:
+		ok = x == bits && literal[0] != '-'
+	}
+	if !ok {
+		return constMismatch(s.Name, scalar, literal)
+	}
+	return nil
+}
+
+// sameConstant reports whether two constant literals have the same value.
+func sameConstant(a, b string) bool {
+	x, okA := parseConst(a)
+	y, okB := parseConst(b)
+	return okA && okB && x == y
+}
+
+// resolveConst resolves a constant field of the writer as the untagged field,
+// checked against the constant in the type of the reader.
+func (tb *TinyBin) resolveConst(w *Schema, t reflect.Type) (Codec, error) {
+	literal, _ := w.constant()
+	plain := *w
+	plain.Tag = tagOptions(w.Tag).without("const")
+	codec, err := tb.resolve(&plain, t)
+	if err != nil {
+		return nil, err
+	}
+	return newConstCodec(t, codec, w.Name, literal)
+}
@@ -0,0 +1,140 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type magicFrame struct {
+	Magic   uint16 `binary:"const=0xCAFE,fixed,be"`
+	Version uint8  `binary:"const=2"`
+	Offset  int8   `binary:"const=-1"`
+	Payload string
+}
+
+func TestConstTagLayout(t *testing.T) {
+	tb := New()
+
+	// Constants are written whatever the field values
+	data, err := tb.Encode(&magicFrame{Payload: "hi"})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0xCA, 0xFE, 2, 1, 2, 'h', 'i'}, data)
+
+	var out magicFrame
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, magicFrame{Magic: 0xCAFE, Version: 2, Offset: -1, Payload: "hi"}, out)
+
+	for _, frame := range [][]byte{
+		{0xCA, 0xFF, 2, 1, 0}, // wrong magic
+		{0xCA, 0xFE, 3, 1, 0}, // wrong version
+		{0xCA, 0xFE, 2, 2, 0}, // wrong offset
+	} {
+		if err := tb.Decode(frame, &out); err == nil {
+			t.Errorf("Expected error decoding %v", frame)
+		}
+	}
+}
+
+func TestConstTagInvalid(t *testing.T) {
+	tb := New()
+	for _, in := range []any{
+		&struct {
+			V uint8 `binary:"const=256"`
+		}{},
+		&struct {
+			V uint8 `binary:"const=-1"`
+		}{},
+		&struct {
+			V int8 `binary:"const=0x80"`
+		}{},
+		&struct {
+			V int64 `binary:"const=0xFFFFFFFFFFFFFFFF"`
+		}{},
+		&struct {
+			V uint16 `binary:"const=0xZZ"`
+		}{},
+		&struct {
+			V uint64 `binary:"const=0x10000000000000000"`
+		}{},
+		&struct {
+			V string `binary:"const=1"`
+		}{},
+		&struct {
+			V uint8 `binary:"bits=4,const=1"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for invalid constant in %T", in)
+		}
+	}
+
+	var bounds struct {
+		Min int8   `binary:"const=-128"`
+		Max uint64 `binary:"const=0xffffffffffffffff"`
+	}
+	data, err := tb.Encode(&bounds)
+	assertNoError(t, err)
+	assertNoError(t, tb.Decode(data, &bounds))
+	assertEqual(t, int8(-128), bounds.Min)
+	assertEqual(t, uint64(1<<64-1), bounds.Max)
+}
+
+func TestConstTagSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(magicFrame{})
+	assertNoError(t, err)
+
+	data, err := tb.Encode(&magicFrame{Payload: "hi"})
+	assertNoError(t, err)
+	foreign := append([]byte{0xBE, 0xEF}, data[2:]...)
+
+	// Readers may drop the tag and widen the field, the constant is still checked
+	var plain struct {
+		Magic   uint32
+		Payload string
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &plain))
+	assertEqual(t, uint32(0xCAFE), plain.Magic)
+	assertEqual(t, "hi", plain.Payload)
+	if err := tb.DecodeWithWriterSchema(foreign, schema, &plain); err == nil {
+		t.Error("Expected error resolving a wrong magic number")
+	}
+
+	var payload struct{ Payload string }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &payload))
+	assertEqual(t, "hi", payload.Payload)
+	if err := tb.DecodeWithWriterSchema(foreign, schema, &payload); err == nil {
+		t.Error("Expected error skipping a wrong magic number")
+	}
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, magicFrame{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&magicFrame{}))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	magic, _ := v.Field("Magic")
+	assertEqual(t, uint64(0xCAFE), magic.Scalar)
+	offset, _ := v.Field("Offset")
+	assertEqual(t, int64(-1), offset.Scalar)
+
+	old, err := tb.Schema(struct {
+		V uint8 `binary:"const=0x10"`
+	}{})
+	assertNoError(t, err)
+	same, err := tb.Schema(struct {
+		V uint8 `binary:"const=16"`
+	}{})
+	assertNoError(t, err)
+	assertEqualInt(t, 0, len(CheckCompatibility(old, same)))
+	cur, err := tb.Schema(struct {
+		V uint8 `binary:"const=17"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "constant changed", issues[0].Reason)
+}
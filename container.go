@@ -0,0 +1,449 @@
+package tinybin
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"math"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Container files start with a magic number and a format version, followed by
+// the schema of the record type, the layout flags of the writer and blocks of
+// encoded records. Each block is the uvarint record count, the uvarint byte
+// length, the records and the little-endian CRC-32C of the records. Files of
+// version 1 have no layout flags.
+const (
+	containerMagic   = "TBIN"
+	containerVersion = 2
+
+	// DefaultBlockSize is the number of records buffered before a block is written.
+	DefaultBlockSize = 128
+)
+
+// Layout flags, the instance options changing the bytes of the records.
+const (
+	layoutFixedWidth byte = 1 << iota
+	layoutBigEndian
+	layoutAlign
+	layoutAutoDelta
+	layoutBitPacking
+)
+
+// layoutFlags returns the layout flags of the instance.
+func (tb *TinyBin) layoutFlags() (flags byte) {
+	if tb.fixedWidth {
+		flags |= layoutFixedWidth
+	}
+	if tb.bigEndian {
+		flags |= layoutBigEndian
+	}
+	if tb.align {
+		flags |= layoutAlign
+	}
+	if tb.autoDelta {
+		flags |= layoutAutoDelta
+	}
+	if tb.bitPacking {
+		flags |= layoutBitPacking
+	}
+	return flags
+}
+
+// ------------------------------------------------------------------------------
+
+// ContainerWriter writes records of a single type into a self-describing
+// container file.
+type ContainerWriter struct {
+	// BlockSize is the number of records buffered before a block is written.
+	BlockSize int
+
+	tb    *TinyBin
+	out   io.Writer
+	typ   reflect.Type
+	codec Codec
+	enc   encoder
+	block bytes.Buffer
+	count int
+}
+
+// NewContainerWriter writes the container header, including the schema of the
+// sample's type, and returns a writer accepting records of that type.
+func (tb *TinyBin) NewContainerWriter(w io.Writer, sample any) (*ContainerWriter, error) {
+	schema, err := tb.Schema(sample)
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.Indirect(reflect.ValueOf(sample)).Type()
+	codec, err := tb.scanToCache(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	cw := &ContainerWriter{BlockSize: DefaultBlockSize, tb: tb, out: w, typ: typ, codec: codec}
+
+	// Header: magic, format version, the serialized schema and layout flags
+	encoded, err := schema.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	cw.enc.Reset(w, tb)
+	cw.enc.Write([]byte(containerMagic))
+	cw.enc.WriteUvarint(containerVersion)
+	cw.enc.WriteUvarint(uint64(len(encoded)))
+	cw.enc.Write(encoded)
+	cw.enc.Write([]byte{tb.layoutFlags()})
+	if cw.enc.err != nil {
+		return nil, cw.enc.err
+	}
+
+	cw.enc.Reset(&cw.block, tb)
+	return cw, nil
+}
+
+// Append adds a record to the current block, writing the block once it holds
+// BlockSize records.
+func (w *ContainerWriter) Append(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() || rv.Type() != w.typ {
+		return Err("container", D.Type, D.Mismatch)
+	}
+
+	if err := w.codec.EncodeTo(&w.enc, rv); err != nil {
+		return err
+	}
+	if w.enc.err != nil {
+		return w.enc.err
+	}
+
+	if w.count++; w.count >= w.BlockSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered records as a block.
+func (w *ContainerWriter) Flush() error {
+	if w.count == 0 {
+		return nil
+	}
+
+	var e encoder
+	e.Reset(w.out, w.tb)
+	e.WriteUvarint(uint64(w.count))
+	e.WriteUvarint(uint64(w.block.Len()))
+	e.Write(w.block.Bytes())
+	e.WriteUint32(crc32.Checksum(w.block.Bytes(), castagnoliTable))
+
+	w.block.Reset()
+	w.count = 0
+	return e.err
+}
+
+// Close flushes the last block. The underlying writer is not closed.
+func (w *ContainerWriter) Close() error {
+	return w.Flush()
+}
+
+// ------------------------------------------------------------------------------
+
+// ContainerReader reads the records of a container file, either into Go values
+// of a compatible type or as dynamic values following the embedded schema.
+type ContainerReader struct {
+	tb     *TinyBin
+	in     reader
+	schema Schema
+	dec    decoder
+	block  []byte
+	left   uint64 // records left in the current block
+
+	// Resolved codec for the last target type
+	typ   reflect.Type
+	codec Codec
+}
+
+// NewContainerReader reads the container header and returns a reader
+// positioned at the first record.
+func (tb *TinyBin) NewContainerReader(r io.Reader) (*ContainerReader, error) {
+	cr := &ContainerReader{tb: tb, in: newReader(r)}
+
+	magic := make([]byte, len(containerMagic))
+	if _, err := io.ReadFull(cr.in, magic); err != nil || string(magic) != containerMagic {
+		return nil, Err("container", D.Format, D.Invalid)
+	}
+	version, err := cr.in.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if version != 1 && version != containerVersion {
+		return nil, Errf("container format version %d not supported", version)
+	}
+
+	l, err := cr.in.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > math.MaxInt32 {
+		return nil, Err("container", D.Format, D.Invalid)
+	}
+
+	// The schema grows as it is read, so a corrupted length can not allocate
+	// more than the file holds
+	encoded, err := io.ReadAll(io.LimitReader(cr.in, int64(l)))
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) != int(l) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err = cr.schema.UnmarshalBinary(encoded); err != nil {
+		return nil, err
+	}
+
+	// Records are only readable with the options they were written with
+	if version > 1 {
+		flags, err := cr.in.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if flags != tb.layoutFlags() {
+			return nil, Err("container", "layout", D.Options, D.Mismatch)
+		}
+	}
+
+	cr.dec.Reset(nil, tb)
+	return cr, nil
+}
+
+// Schema returns the schema of the records, as written in the file header.
+func (r *ContainerReader) Schema() Schema {
+	return r.schema
+}
+
+// Next decodes the next record into v, converting compatible representations
+// as DecodeWithWriterSchema does. It returns io.EOF after the last record.
+func (r *ContainerReader) Next(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.CanAddr() {
+		return Err(D.Binary, "decoder", D.Required, D.Type, D.Pointer)
+	}
+
+	if rv.Type() != r.typ {
+		codec, err := r.tb.resolve(&r.schema, rv.Type())
+		if err != nil {
+			return err
+		}
+		r.typ, r.codec = rv.Type(), codec
+	}
+
+	if err := r.nextRecord(); err != nil {
+		return err
+	}
+	return r.codec.DecodeTo(&r.dec, rv)
+}
+
+// NextValue decodes the next record without a Go type. It returns io.EOF
+// after the last record.
+func (r *ContainerReader) NextValue() (Value, error) {
+	if err := r.nextRecord(); err != nil {
+		return Value{}, err
+	}
+	return decodeValue(&r.dec, &r.schema)
+}
+
+// nextRecord loads the next block when the current one is exhausted.
+func (r *ContainerReader) nextRecord() error {
+	for r.left == 0 {
+		count, err := r.in.ReadUvarint()
+		if err != nil {
+			return err // io.EOF at a block boundary is the end of the file
+		}
+		l, err := r.in.ReadUvarint()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if l > math.MaxInt32 {
+			return ErrChecksum
+		}
+
+		if cap(r.block) < int(l)+4 {
+			r.block = make([]byte, int(l)+4)
+		}
+		r.block = r.block[:int(l)+4]
+		if _, err = io.ReadFull(r.in, r.block); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		records := r.block[:l]
+		if crc32.Checksum(records, castagnoliTable) != readUint32(r.block[l:]) {
+			return ErrChecksum
+		}
+
+		r.dec.reader.(*sliceReader).Reset(records)
+		r.left = count
+	}
+
+	r.left--
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// Value is a record decoded without a Go type, following the writer schema.
+type Value struct {
+	Name   string  // Field name when the value is part of a struct
+	Kind   Kind    // Go kind of the writer type
+	Scalar any     // bool, int64, uint64, float32, float64, string or []byte
+	Items  []Value // Struct fields, slice or array elements, or the target of a pointer
+	Nil    bool    // Nil pointer, or conditional field left out
+}
+
+// Field returns the struct field with the given name.
+func (v Value) Field(name string) (Value, bool) {
+	if v.Kind == K.Struct {
+		for _, item := range v.Items {
+			if item.Name == name {
+				return item, true
+			}
+		}
+	}
+	return Value{}, false
+}
+
+// decodeValue decodes a value described by the schema into a dynamic tree.
+func decodeValue(d *decoder, s *Schema) (v Value, err error) {
+	v = Value{Name: s.Name, Kind: s.Kind}
+	if order := s.byteOrder(); order != orderDefault {
+		prev := d.order
+		d.order = order
+		defer func() { d.order = prev }()
+	}
+
+	switch s.wire() {
+	case wireBool:
+		v.Scalar, err = d.ReadBool()
+	case wireVarint:
+		if d.fixedInts() {
+			v.Scalar, err = readFixedScalar(d, fixedSize(s.Kind), true)
+			break
+		}
+		v.Scalar, err = d.ReadVarint()
+	case wireUvarint:
+		if d.fixedInts() {
+			v.Scalar, err = readFixedScalar(d, fixedSize(s.Kind), false)
+			break
+		}
+		v.Scalar, err = d.ReadUvarint()
+	case wireFixed:
+		v.Scalar, err = readFixedScalar(d, s.fixedWidth(), signedKind(s.Kind))
+	case wireSized, wireCString:
+		err = decodeText(d, s, &v)
+	case wireFloat32:
+		v.Scalar, err = d.ReadFloat32()
+	case wireFloat64:
+		v.Scalar, err = d.ReadFloat64()
+	case wireBytes:
+		if d.dict != nil && s.dictString() {
+			v.Scalar, err = d.readDictString()
+			break
+		}
+		var b []byte
+		if b, err = d.ReadSlice(); err == nil {
+			if s.Kind == K.String {
+				v.Scalar = string(b)
+			} else {
+				v.Scalar = append([]byte(nil), b...)
+			}
+		}
+	case wireDelta:
+		err = decodeIntItems(d, s, &v)
+	case wireXORFloat:
+		err = decodeFloatItems(d, s, &v)
+	case wireSlice:
+		if d.adaptiveInts() && isIntWire(s.Elem) {
+			err = decodeIntItems(d, s, &v)
+			break
+		}
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil {
+			err = decodeItems(d, s.Elem, int(l), &v)
+		}
+	case wireArray:
+		err = decodeItems(d, s.Elem, s.Len, &v)
+	case wirePointer:
+		if v.Nil, err = d.ReadBool(); err == nil && !v.Nil {
+			err = decodeItems(d, s.Elem, 1, &v)
+		}
+	case wireStruct:
+		v.Items = make([]Value, len(s.Fields))
+		pads := d.structPads(s)
+		for i := 0; i < len(s.Fields) && err == nil; i = bitRun(s.Fields, i) {
+			if err = d.skipPad(pads, i); err == nil {
+				err = decodeField(d, s.Fields, i, v.Items)
+			}
+		}
+		if err == nil {
+			err = d.skipPad(pads, len(s.Fields))
+		}
+	case wireBits:
+		var values []Value
+		if values, err = decodeBitValues(d, []Schema{*s}); err == nil {
+			v.Scalar = values[0].Scalar
+		}
+	case wireColumnar:
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil && l > d.tb.messageLimit()*8 {
+			err = ErrMessageSize // Every row takes at least one bit
+		} else if err == nil && l > 0 {
+			// Rows are added as the first column is read, the length being untrusted
+			v.Items = make([]Value, 0, min(l, 1024))
+			for f := 0; f < len(s.Elem.Fields) && err == nil; f = bitRun(s.Elem.Fields, f) {
+				for i := 0; i < int(l) && err == nil; i++ {
+					if f == 0 {
+						v.Items = append(v.Items, Value{Name: s.Elem.Name, Kind: s.Elem.Kind, Items: make([]Value, len(s.Elem.Fields))})
+					}
+					err = decodeField(d, s.Elem.Fields, f, v.Items[i].Items)
+				}
+			}
+		}
+	default:
+		err = Err(D.Type, s.Type, D.Not, D.Supported)
+	}
+	if err == nil {
+		err = s.checkConstant(v.Scalar)
+	}
+	return v, err
+}
+
+// decodeField decodes field i of a struct into items[i], along with the
+// bitfields packed with it.
+func decodeField(d *decoder, fields []Schema, i int, items []Value) (err error) {
+	if fields[i].bitWidth() > 0 {
+		var values []Value
+		if values, err = decodeBitValues(d, fields[i:bitRun(fields, i)]); err == nil {
+			copy(items[i:], values)
+		}
+		return err
+	}
+	if !present(fields, i, items) {
+		items[i] = Value{Name: fields[i].Name, Kind: fields[i].Kind, Nil: true}
+		return nil
+	}
+	if fields[i].wire() == wireCounted {
+		items[i], err = decodeCounted(d, fields, i, items)
+		return err
+	}
+	items[i], err = decodeValue(d, &fields[i])
+	return err
+}
+
+func decodeItems(d *decoder, elem *Schema, n int, v *Value) (err error) {
+	for i := 0; i < n && err == nil; i++ {
+		var item Value
+		if item, err = decodeValue(d, elem); err == nil {
+			v.Items = append(v.Items, item)
+		}
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package tinybin
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+type archivedReading struct {
+	Sensor string
+	Value  float32
+	Flags  []uint8
+	Prev   *int32
+}
+
+type archivedReadingV2 struct {
+	Sensor string
+	Value  float64
+	Prev   int64
+	Note   string
+}
+
+func writeTestContainer(t *testing.T, tb *TinyBin, n int) *bytes.Buffer {
+	t.Helper()
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, archivedReading{})
+	assertNoError(t, err)
+	w.BlockSize = 3
+
+	prev := int32(-5)
+	for i := 0; i < n; i++ {
+		assertNoError(t, w.Append(&archivedReading{Sensor: "s", Value: float32(i), Flags: []uint8{1}, Prev: &prev}))
+	}
+	assertNoError(t, w.Close())
+	return &file
+}
+
+func TestContainerTypedRead(t *testing.T) {
+	tb := New()
+	file := writeTestContainer(t, tb, 7)
+
+	r, err := New().NewContainerReader(file)
+	assertNoError(t, err)
+	assertEqual(t, "tinybin.archivedReading", r.Schema().Type)
+
+	for i := 0; i < 7; i++ {
+		var v archivedReadingV2
+		assertNoError(t, r.Next(&v))
+		assertEqual(t, archivedReadingV2{Sensor: "s", Value: float64(i), Prev: -5}, v)
+	}
+
+	var v archivedReadingV2
+	if err := r.Next(&v); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestContainerDynamicRead(t *testing.T) {
+	file := writeTestContainer(t, New(), 2)
+
+	r, err := New().NewContainerReader(file)
+	assertNoError(t, err)
+
+	v, err := r.NextValue()
+	assertNoError(t, err)
+
+	sensor, ok := v.Field("Sensor")
+	assertEqual(t, true, ok)
+	assertEqual(t, "s", sensor.Scalar)
+
+	flags, _ := v.Field("Flags")
+	assertEqual(t, []byte{1}, flags.Scalar)
+
+	prev, _ := v.Field("Prev")
+	assertEqual(t, false, prev.Nil)
+	assertEqual(t, int64(-5), prev.Items[0].Scalar)
+
+	v, err = r.NextValue()
+	assertNoError(t, err)
+	value, _ := v.Field("Value")
+	assertEqual(t, float32(1), value.Scalar)
+}
+
+func TestContainerCorruptedBlock(t *testing.T) {
+	file := writeTestContainer(t, New(), 2)
+	data := file.Bytes()
+	data[len(data)-6] ^= 0xFF
+
+	r, err := New().NewContainerReader(bytes.NewReader(data))
+	assertNoError(t, err)
+
+	var v archivedReading
+	if err := r.Next(&v); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+}
+
+func TestContainerRejectsForeignData(t *testing.T) {
+	if _, err := New().NewContainerReader(bytes.NewReader([]byte("JUNKDATA"))); err == nil {
+		t.Error("Expected error for missing magic number")
+	}
+
+	var file bytes.Buffer
+	w, err := New().NewContainerWriter(&file, archivedReading{})
+	assertNoError(t, err)
+	if err := w.Append(&basicStruct{}); err == nil {
+		t.Error("Expected error for record of another type")
+	}
+}
+
+func TestContainerRejectsCorruptedHeader(t *testing.T) {
+	schema := []byte{0, 0, byte(K.Struct), 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}
+	data := append([]byte(containerMagic), containerVersion, byte(len(schema)))
+	if _, err := New().NewContainerReader(bytes.NewReader(append(data, schema...))); err == nil {
+		t.Error("Expected error for corrupted schema field count")
+	}
+
+	// A schema length larger than the file
+	data = append([]byte(containerMagic), containerVersion, 0xFF, 0xFF, 0xFF, 0x7F)
+	if _, err := New().NewContainerReader(bytes.NewReader(data)); err == nil {
+		t.Error("Expected error for truncated schema")
+	}
+}
+
+func TestContainerRejectsOtherLayout(t *testing.T) {
+	type counters struct {
+		A []int32
+		B uint16
+	}
+	in := counters{A: []int32{-1, 0, 0}, B: 7}
+
+	var file bytes.Buffer
+	w, err := New(FixedWidth{}).NewContainerWriter(&file, counters{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+
+	for _, tb := range []*TinyBin{New(), New(AutoDelta{}), New(CLayout{BigEndian: true})} {
+		if _, err := tb.NewContainerReader(bytes.NewReader(file.Bytes())); err == nil {
+			t.Error("Expected error reading a container written with other options")
+		}
+	}
+
+	// CLayout without BigEndian or Align writes the same bytes as FixedWidth
+	r, err := New(CLayout{}).NewContainerReader(bytes.NewReader(file.Bytes()))
+	assertNoError(t, err)
+	var out counters
+	assertNoError(t, r.Next(&out))
+	assertEqual(t, in, out)
+}
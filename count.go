@@ -0,0 +1,314 @@
+package tinybin
+
+import (
+	"io"
+	"math"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// countedCodec encodes a string, []byte or slice field tagged with
+// `binary:"len=Count"`: the elements without any length prefix, the length
+// being the value of the earlier field Count. It reads the whole struct, so its
+// fieldCodec has an Index of -1.
+type countedCodec struct {
+	index     int    // The index of the counted field
+	count     int    // The index of the field holding the length
+	countName string // The name of the field holding the length
+	elemCodec Codec  // The codec of the elements, nil for strings and []byte
+}
+
+// newCountedCodec returns the counted codec of a field, before its count field
+// is linked by linkCounts.
+func newCountedCodec(field reflect.StructField, countName string) (Codec, error) {
+	c := &countedCodec{index: field.Index[0], count: -1, countName: countName}
+	switch {
+	case field.Type.Kind() == reflect.String:
+	case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Uint8:
+	case field.Type.Kind() == reflect.Slice:
+		elemCodec, err := scanType(field.Type.Elem())
+		if err != nil {
+			return nil, err
+		}
+		c.elemCodec = elemCodec
+	default:
+		return nil, Err("len", D.Type, field.Type.String(), D.Not, D.Supported)
+	}
+	return c, nil
+}
+
+func (c *countedCodec) fieldIndex() int {
+	return c.index
+}
+
+// Encode encodes a value into the encoder.
+func (c *countedCodec) EncodeTo(e *encoder, rv reflect.Value) (err error) {
+	field := rv.Field(c.index)
+	switch {
+	case field.Kind() == reflect.String:
+		e.Write(ToBytes(field.String()))
+	case c.elemCodec == nil:
+		e.Write(field.Bytes())
+	default:
+		for i := 0; i < field.Len() && err == nil; i++ {
+			err = c.elemCodec.EncodeTo(e, field.Index(i))
+		}
+	}
+	return err
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *countedCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	n, err := countOf(d, rv.Field(c.count))
+	if err != nil {
+		return err
+	}
+
+	field := rv.Field(c.index)
+	switch {
+	case field.Kind() == reflect.String:
+		b, err := d.Slice(n)
+		if err != nil {
+			return err
+		}
+		field.SetString(string(b))
+	case c.elemCodec == nil:
+		b, err := d.Slice(n)
+		if err != nil {
+			return err
+		}
+		field.SetBytes(append([]byte(nil), b...))
+	default:
+		field.Set(reflect.MakeSlice(field.Type(), n, n))
+		for i := 0; i < n; i++ {
+			if err = c.elemCodec.DecodeTo(d, field.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countOf returns the length held by a count field.
+func countOf(d *decoder, rv reflect.Value) (int, error) {
+	if rv.CanInt() {
+		n := rv.Int()
+		if n < 0 {
+			return 0, Errf("negative length %d in field of type %s", n, rv.Type().String())
+		}
+		return d.checkCount(uint64(n))
+	}
+	return d.checkCount(rv.Uint())
+}
+
+// checkCount bounds a decoded count before anything is allocated for it: every
+// element takes at least one byte, so it can not exceed the input left nor the
+// MaxMessageSize of the instance.
+func (d *decoder) checkCount(n uint64) (int, error) {
+	if r, ok := d.reader.(*sliceReader); ok && n > uint64(r.Len()) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if d.tb != nil && n > d.tb.messageLimit() || n > math.MaxInt {
+		return 0, ErrMessageSize
+	}
+	return int(n), nil
+}
+
+// ------------------------------------------------------------------------------
+
+// lengthCodec writes the length of the fields tagged with `binary:"len=..."`
+// in place of the value of the count field they name, so the two can not
+// disagree. It reads the whole struct, so its fieldCodec has an Index of -1.
+type lengthCodec struct {
+	index   int                 // The index of the count field
+	counted []int               // The indexes of the fields counted by it
+	conds   []*conditionalCodec // The conditions of the counted fields, nil if unconditional
+	codec   Codec               // The codec of the count field
+}
+
+func (c *lengthCodec) fieldIndex() int {
+	return c.index
+}
+
+// lengthOf returns the length written for counted field k, 0 when its
+// condition leaves it out.
+func (c *lengthCodec) lengthOf(rv reflect.Value, k int) int {
+	if cond := c.conds[k]; cond != nil && !cond.present(rv) {
+		return 0
+	}
+	return rv.Field(c.counted[k]).Len()
+}
+
+// Encode encodes a value into the encoder.
+func (c *lengthCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	n := c.lengthOf(rv, 0)
+	for k, i := range c.counted[1:] {
+		if c.lengthOf(rv, k+1) != n {
+			return Errf("fields %s and %s counted by %s have different lengths",
+				rv.Type().Field(c.counted[0]).Name, rv.Type().Field(i).Name, rv.Type().Field(c.index).Name)
+		}
+	}
+
+	count := reflect.New(rv.Field(c.index).Type()).Elem()
+	if count.CanInt() {
+		if count.OverflowInt(int64(n)) {
+			return Errf("length %d overflows field %s", n, rv.Type().Field(c.index).Name)
+		}
+		count.SetInt(int64(n))
+	} else {
+		if count.OverflowUint(uint64(n)) {
+			return Errf("length %d overflows field %s", n, rv.Type().Field(c.index).Name)
+		}
+		count.SetUint(uint64(n))
+	}
+	return c.codec.EncodeTo(e, count)
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *lengthCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	return c.codec.DecodeTo(d, rv.Field(c.index))
+}
+
+// linkCounts links the counted fields of a struct to their count fields, which
+// must be earlier integer fields not packed as bitfields nor constant, since
+// their value is the length of the fields they count.
+func linkCounts(t reflect.Type, fields reflectStructCodec) error {
+	for i := range fields {
+		counted, ok := fields[i].Codec.(*countedCodec)
+		if !ok {
+			continue
+		}
+
+		count := -1
+		for j := 0; j < i; j++ {
+			if fields[j].field() >= 0 && t.Field(fields[j].field()).Name == counted.countName {
+				count = j
+			}
+		}
+		if count < 0 {
+			return Err("len", t.Field(counted.index).Name, D.Field, counted.countName, D.Not, D.Found)
+		}
+
+		index := fields[count].field()
+		k := Kind(t.Field(index).Type.Kind())
+		if !signedKind(k) && !unsignedKind(k) {
+			return Err("len", D.Field, counted.countName, D.Type, t.Field(index).Type.String(), D.Not, D.Supported)
+		}
+		if tagOptions(t.Field(index).Tag.Get("binary")).Has("const") {
+			return Err("len", D.Field, counted.countName, "const", D.Not, D.Supported)
+		}
+		counted.count = index
+		fields[i].Index = -1
+
+		if length, ok := fields[count].Codec.(*lengthCodec); ok {
+			length.counted = append(length.counted, counted.index)
+			length.conds = append(length.conds, nil)
+			continue
+		}
+		fields[count] = fieldCodec{Index: -1, Codec: &lengthCodec{index: index, counted: []int{counted.index}, conds: []*conditionalCodec{nil}, codec: fields[count].Codec}}
+	}
+	return nil
+}
+
+// countedSkipCodec skips a counted writer field missing from the reader, whose
+// length the reader decoded into its own count field.
+type countedSkipCodec struct {
+	schema *Schema // The writer field
+	count  int     // The index of the reader count field
+}
+
+// Encode is not supported, the codec only reads.
+func (c *countedSkipCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode reads and discards the value.
+func (c *countedSkipCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	n, err := countOf(d, rv.Field(c.count))
+	if err != nil {
+		return err
+	}
+	if c.schema.Kind == K.String || c.schema.Elem.Kind == K.Uint8 {
+		_, err = d.Slice(n)
+		return err
+	}
+	for i := 0; i < n && err == nil; i++ {
+		err = skip(d, c.schema.Elem)
+	}
+	return err
+}
+
+// resolveCountedSkip returns the codec skipping a counted writer field missing
+// from the reader, which needs the count field to read its length.
+func resolveCountedSkip(w *Schema, t reflect.Type) (Codec, error) {
+	field, ok := t.FieldByName(w.countField())
+	if !ok || len(field.Index) != 1 || field.Tag.Get("binary") == "-" ||
+		!signedKind(Kind(field.Type.Kind())) && !unsignedKind(Kind(field.Type.Kind())) {
+		return nil, Err("len", w.Name, D.Field, w.countField(), D.Not, D.Found)
+	}
+	return &countedSkipCodec{schema: w, count: field.Index[0]}, nil
+}
+
+// ------------------------------------------------------------------------------
+
+// countField returns the name of the field holding the length of a field tagged
+// with `binary:"len=..."`, or "" for other fields.
+func (s *Schema) countField() string {
+	name, _ := tagOptions(s.Tag).Get("len")
+	return name
+}
+
+// hasLinkedFields reports whether a struct holds counted or conditional
+// fields, which can only be read once the fields before them are decoded.
+func (s *Schema) hasLinkedFields() bool {
+	for i := range s.Fields {
+		if _, ok := s.Fields[i].condition(); ok || s.Fields[i].countField() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeCounted decodes field i of a struct, counted by an earlier field
+// already decoded into items.
+func decodeCounted(d *decoder, fields []Schema, i int, items []Value) (v Value, err error) {
+	s := &fields[i]
+	v = Value{Name: s.Name, Kind: s.Kind}
+
+	var n uint64
+	for j := 0; j < i; j++ {
+		if fields[j].Name != s.countField() {
+			continue
+		}
+		switch x := items[j].Scalar.(type) {
+		case int64:
+			if x < 0 {
+				return v, Errf("negative length %d in field %s", x, fields[j].Name)
+			}
+			n = uint64(x)
+		case uint64:
+			n = x
+		}
+	}
+	count, err := d.checkCount(n)
+	if err != nil {
+		return v, err
+	}
+
+	switch {
+	case s.Kind == K.String:
+		var b []byte
+		if b, err = d.Slice(count); err == nil {
+			v.Scalar = string(b)
+		}
+	case s.Elem.Kind == K.Uint8:
+		var b []byte
+		if b, err = d.Slice(count); err == nil {
+			v.Scalar = append([]byte(nil), b...)
+		}
+	default:
+		err = decodeItems(d, s.Elem, count, &v)
+	}
+	return v, err
+}
@@ -0,0 +1,253 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type vendorItem struct {
+	Code  uint8
+	Value int16 `binary:"fixed"`
+}
+
+type vendorFrame struct {
+	Type  uint8
+	Count uint8
+	Items []vendorItem `binary:"len=Count"`
+	Size  uint16       `binary:"fixed"`
+	Name  string       `binary:"len=Size"`
+	Raw   []byte       `binary:"len=Size"`
+}
+
+func TestCountTagLayout(t *testing.T) {
+	tb := New()
+	in := vendorFrame{Type: 7, Items: []vendorItem{{Code: 1, Value: 2}, {Code: 3, Value: -1}}, Name: "ab", Raw: []byte{9, 8}}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		7,                      // Type
+		2,                      // Count, taken from Items
+		1, 2, 0, 3, 0xFF, 0xFF, // Items
+		2, 0, // Size, taken from Name and Raw
+		'a', 'b', // Name
+		9, 8, // Raw
+	}, data)
+
+	var out vendorFrame
+	assertNoError(t, tb.Decode(data, &out))
+	in.Count, in.Size = 2, 2
+	assertEqual(t, in, out)
+
+	// Empty fields
+	data, err = tb.Encode(&vendorFrame{Type: 1})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 0, 0, 0}, data)
+}
+
+func TestCountTagErrors(t *testing.T) {
+	tb := New()
+
+	// Fields sharing a count must agree
+	if _, err := tb.Encode(&vendorFrame{Name: "abc", Raw: []byte{1}}); err == nil {
+		t.Error("Expected error for fields of different lengths")
+	}
+
+	// The length must fit the count field
+	if _, err := tb.Encode(&vendorFrame{Items: make([]vendorItem, 256)}); err == nil {
+		t.Error("Expected error for a length overflowing the count field")
+	}
+
+	// The frame is shorter than its count
+	var out vendorFrame
+	if err := tb.Decode([]byte{1, 3, 1, 2, 0}, &out); err == nil {
+		t.Error("Expected error decoding a truncated frame")
+	}
+
+	for _, in := range []any{
+		&struct {
+			Items []uint8 `binary:"len=Count"`
+			Count uint8
+		}{},
+		&struct {
+			Count string
+			Items []uint8 `binary:"len=Count"`
+		}{},
+		&struct {
+			Count uint8
+			Items [4]uint8 `binary:"len=Count"`
+		}{},
+		&struct {
+			Count uint8   `binary:"const=4"`
+			Items []uint8 `binary:"len=Count"`
+		}{Items: []uint8{1, 2}},
+		// Options changing the encoding of the counted field are not applied
+		&struct {
+			Count uint8
+			Name  string `binary:"len=Count,cstring"`
+		}{},
+		&struct {
+			Count uint8
+			Name  string `binary:"len=Count,size=8"`
+		}{},
+		&struct {
+			Count uint8
+			Items []uint32 `binary:"len=Count,fixed,be"`
+		}{},
+		&struct {
+			Count uint8
+			Items []int64 `binary:"len=Count,delta"`
+		}{},
+		&struct {
+			Count  uint8
+			Secret []byte `binary:"len=Count,encrypt"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for invalid count in %T", in)
+		}
+	}
+
+	var negative struct {
+		Count int8
+		Name  string `binary:"len=Count"`
+	}
+	if err := tb.Decode([]byte{0x01}, &negative); err == nil {
+		t.Error("Expected error for a negative count")
+	}
+
+	// Corrupted counts are rejected before anything is allocated
+	huge := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01, 1, 2}
+	for _, out := range []any{
+		&struct {
+			Count uint64
+			Name  string `binary:"len=Count"`
+		}{},
+		&struct {
+			Count uint64
+			Raw   []byte `binary:"len=Count"`
+		}{},
+		&struct {
+			Count uint64
+			Items []uint16 `binary:"len=Count"`
+		}{},
+	} {
+		if err := tb.Decode(huge, out); err == nil {
+			t.Errorf("Expected error for a corrupted count in %T", out)
+		}
+		if err := tb.DecodeFrom(bytes.NewReader(huge), out); err == nil {
+			t.Errorf("Expected error for a corrupted count read from a stream in %T", out)
+		}
+	}
+	schema, err := tb.Schema(struct {
+		Count uint64
+		Name  string `binary:"len=Count"`
+	}{})
+	assertNoError(t, err)
+	if _, err := decodeValue(&decoder{reader: newSliceReader(huge), tb: tb}, &schema); err == nil {
+		t.Error("Expected error for a corrupted count decoded without a Go type")
+	}
+}
+
+func TestCountTagSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(vendorFrame{})
+	assertNoError(t, err)
+	assertEqual(t, wireCounted, schema.Fields[2].wire())
+
+	in := vendorFrame{Type: 7, Items: []vendorItem{{Code: 1, Value: 2}, {Code: 3, Value: -1}}, Name: "ab", Raw: []byte{9, 8}}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	// Readers may drop fields and widen the count field
+	var subset struct {
+		Count uint32
+		Items []vendorItem `binary:"len=Count"`
+		Size  uint64
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, uint32(2), subset.Count)
+	assertEqual(t, in.Items, subset.Items)
+	assertEqual(t, uint64(2), subset.Size)
+
+	var mismatch struct {
+		Count uint8
+		Items []vendorItem
+	}
+	if err := tb.DecodeWithWriterSchema(data, schema, &mismatch); err == nil {
+		t.Error("Expected error resolving a counted field into a prefixed one")
+	}
+
+	// Skipping a counted field needs its length
+	var noCount struct{ Type uint8 }
+	if err := tb.DecodeWithWriterSchema(data, schema, &noCount); err == nil {
+		t.Error("Expected error skipping counted fields without their count")
+	}
+
+	type nested struct {
+		Frames []vendorFrame
+		After  uint8
+	}
+	nestedSchema, err := tb.Schema(nested{})
+	assertNoError(t, err)
+	data, err = tb.Encode(&nested{Frames: []vendorFrame{in, in}, After: 5})
+	assertNoError(t, err)
+	var after struct{ After uint8 }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, nestedSchema, &after))
+	assertEqual(t, uint8(5), after.After)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, vendorFrame{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	items, _ := v.Field("Items")
+	assertEqualInt(t, 2, len(items.Items))
+	name, _ := v.Field("Name")
+	assertEqual(t, "ab", name.Scalar)
+	raw, _ := v.Field("Raw")
+	assertEqual(t, []byte{9, 8}, raw.Scalar)
+
+	old, err := tb.Schema(struct {
+		Count, Size uint8
+		Name        string `binary:"len=Count"`
+	}{})
+	assertNoError(t, err)
+	cur, err := tb.Schema(struct {
+		Count, Size uint8
+		Name        string `binary:"len=Size"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "length field changed", issues[0].Reason)
+}
+
+func TestCountTagConditional(t *testing.T) {
+	type optionalItems struct {
+		Flag  bool
+		Count uint8
+		Items []uint8 `binary:"len=Count,if=Flag"`
+		Tail  uint8
+	}
+	tb := New()
+
+	// A counted field left out by its condition is counted as empty
+	data, err := tb.Encode(&optionalItems{Items: []uint8{1, 2, 3}, Tail: 9})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0, 0, 9}, data)
+	var out optionalItems
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, optionalItems{Tail: 9}, out)
+
+	data, err = tb.Encode(&optionalItems{Flag: true, Items: []uint8{1, 2, 3}, Tail: 9})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 3, 1, 2, 3, 9}, data)
+	out = optionalItems{}
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, optionalItems{Flag: true, Count: 3, Items: []uint8{1, 2, 3}, Tail: 9}, out)
+}
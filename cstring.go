@@ -0,0 +1,197 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// sizedCodec encodes a string or []byte field tagged with `binary:"size=N"` as
+// exactly N bytes padded with zeros, like a C char array, and a field also
+// tagged with `binary:"cstring"` must leave room for the terminating zero.
+// Strings are decoded up to their first zero byte, []byte fields keep all the N
+// bytes.
+type sizedCodec struct {
+	size       int
+	terminated bool // The value must be followed by at least one zero byte
+}
+
+// newSizedCodec returns the codec of a field tagged with `binary:"size=N"` or
+// `binary:"cstring"`, or nil when the field has neither tag.
+func newSizedCodec(field reflect.StructField, opts tagOptions) (Codec, error) {
+	size, sized := opts.Get("size")
+	terminated := opts.Has("cstring")
+	if !sized && !terminated {
+		return nil, nil
+	}
+	if !isText(field.Type) {
+		return nil, Err("size", D.Type, field.Type.String(), D.Not, D.Supported)
+	}
+	if !sized {
+		return new(cstringCodec), nil
+	}
+
+	n, ok := parseSize(size)
+	if !ok {
+		return nil, Err("size", field.Name, size, D.Invalid)
+	}
+	return &sizedCodec{size: n, terminated: terminated}, nil
+}
+
+// Encode encodes a value into the encoder.
+func (c *sizedCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	b := textBytes(rv)
+	if len(b) > c.size || c.terminated && len(b) == c.size {
+		return Errf("value of %d bytes does not fit size %d", len(b), c.size)
+	}
+
+	e.Write(b)
+	for n := c.size - len(b); n > 0; n -= len(e.scratch) {
+		clear(e.scratch[:])
+		e.Write(e.scratch[:min(n, len(e.scratch))])
+	}
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *sizedCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	b, err := d.Slice(c.size)
+	if err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.String {
+		if i := Index(string(b), "\x00"); i >= 0 {
+			b = b[:i]
+		}
+		rv.SetString(string(b))
+		return nil
+	}
+	rv.SetBytes(append([]byte(nil), b...))
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// cstringCodec encodes a string or []byte field tagged with `binary:"cstring"`
+// as its bytes followed by a zero byte, which the value must not contain.
+type cstringCodec struct{}
+
+// Encode encodes a value into the encoder.
+func (c *cstringCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	b := textBytes(rv)
+	for _, x := range b {
+		if x == 0 {
+			return Errf("value of type %s contains a zero byte", rv.Type().String())
+		}
+	}
+
+	e.Write(b)
+	e.scratch[0] = 0
+	e.Write(e.scratch[:1])
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *cstringCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	b, err := readCString(d)
+	if err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.String {
+		rv.SetString(string(b))
+		return nil
+	}
+	rv.SetBytes(b)
+	return nil
+}
+
+// readCString reads the bytes up to the next zero byte, which it consumes.
+func readCString(d *decoder) ([]byte, error) {
+	var b []byte
+	for {
+		x, err := d.reader.ReadByte()
+		if err != nil || x == 0 {
+			return b, err
+		}
+		b = append(b, x)
+	}
+}
+
+// isText reports whether the type is a string or a []byte.
+func isText(t reflect.Type) bool {
+	return t.Kind() == reflect.String || t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// textBytes returns the bytes of a string or []byte value.
+func textBytes(rv reflect.Value) []byte {
+	if rv.Kind() == reflect.String {
+		return ToBytes(rv.String())
+	}
+	return rv.Bytes()
+}
+
+// parseSize parses the positive decimal size of a tag option.
+func parseSize(s string) (int, bool) {
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' || n > 1<<24 {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, n > 0
+}
+
+// ------------------------------------------------------------------------------
+
+// byteSize returns the size of a string or []byte field tagged with
+// `binary:"size=N"`, or 0 for other fields.
+func (s *Schema) byteSize() int {
+	size, ok := tagOptions(s.Tag).Get("size")
+	if !ok || !s.isText() {
+		return 0
+	}
+	n, _ := parseSize(size)
+	return n
+}
+
+// cstring reports whether the described field is a zero terminated string or
+// []byte, without a size.
+func (s *Schema) cstring() bool {
+	return s.isText() && tagOptions(s.Tag).Has("cstring") && s.byteSize() == 0
+}
+
+func (s *Schema) isText() bool {
+	return s.Kind == K.String || s.Kind == K.Slice && s.Elem != nil && s.Elem.Kind == K.Uint8
+}
+
+// decodeText decodes a sized or zero terminated field without a Go type.
+func decodeText(d *decoder, s *Schema, v *Value) (err error) {
+	var b []byte
+	if s.cstring() {
+		b, err = readCString(d)
+	} else if b, err = d.Slice(s.byteSize()); err == nil && s.Kind == K.String {
+		if i := Index(string(b), "\x00"); i >= 0 {
+			b = b[:i]
+		}
+	}
+
+	if s.Kind == K.String {
+		v.Scalar = string(b)
+	} else {
+		v.Scalar = append([]byte(nil), b...)
+	}
+	return err
+}
+
+// resolveText returns the codec reading a sized or zero terminated field of the
+// writer into a string or []byte.
+func resolveText(w *Schema, t reflect.Type) (Codec, error) {
+	if !isText(t) {
+		return nil, Err(D.Field, w.Name, D.Not, D.Assignable, "from", w.Tag)
+	}
+	if w.cstring() {
+		return new(cstringCodec), nil
+	}
+	return &sizedCodec{size: w.byteSize()}, nil
+}
@@ -0,0 +1,163 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type deviceHeader struct {
+	Name   string `binary:"size=8"`
+	Serial []byte `binary:"size=4"`
+	Label  string `binary:"cstring"`
+	Blob   []byte `binary:"cstring"`
+	Model  string `binary:"size=4,cstring"`
+	Flags  uint8
+}
+
+func TestSizedStringLayout(t *testing.T) {
+	tb := New()
+	in := deviceHeader{Name: "probe", Serial: []byte{1, 2}, Label: "hi", Blob: []byte{7}, Model: "x1", Flags: 3}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		'p', 'r', 'o', 'b', 'e', 0, 0, 0, // Name
+		1, 2, 0, 0, // Serial
+		'h', 'i', 0, // Label
+		7, 0, // Blob
+		'x', '1', 0, 0, // Model
+		3, // Flags
+	}, data)
+
+	var out deviceHeader
+	assertNoError(t, tb.Decode(data, &out))
+	in.Serial = []byte{1, 2, 0, 0}
+	assertEqual(t, in, out)
+
+	// Empty values
+	data, err = tb.Encode(&deviceHeader{})
+	assertNoError(t, err)
+	assertEqualInt(t, 8+4+1+1+4+1, len(data))
+}
+
+func TestSizedStringErrors(t *testing.T) {
+	tb := New()
+	for _, in := range []deviceHeader{
+		{Name: "too long!"},
+		{Serial: []byte{1, 2, 3, 4, 5}},
+		{Label: "a\x00b"},
+		{Blob: []byte{1, 0}},
+		{Model: "abcd"}, // no room for the terminator
+	} {
+		if _, err := tb.Encode(&in); err == nil {
+			t.Errorf("Expected error encoding %+v", in)
+		}
+	}
+
+	// Values filling the whole size are fine
+	in := deviceHeader{Name: "12345678", Model: "abc"}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out deviceHeader
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, "12345678", out.Name)
+	assertEqual(t, "abc", out.Model)
+
+	// Missing terminator
+	var label struct {
+		Label string `binary:"cstring"`
+	}
+	if err := tb.Decode([]byte{'a', 'b'}, &label); err == nil {
+		t.Error("Expected error decoding an unterminated string")
+	}
+
+	for _, in := range []any{
+		&struct {
+			V int `binary:"size=4"`
+		}{},
+		&struct {
+			V string `binary:"size=0"`
+		}{},
+		&struct {
+			V []uint16 `binary:"cstring"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for invalid tag in %T", in)
+		}
+	}
+}
+
+func TestSizedStringSchema(t *testing.T) {
+	tb := New(StringDictionary{})
+	schema, err := tb.Schema(deviceHeader{})
+	assertNoError(t, err)
+	assertEqual(t, wireSized, schema.Fields[0].wire())
+	assertEqual(t, wireCString, schema.Fields[2].wire())
+
+	in := deviceHeader{Name: "probe", Serial: []byte{1, 2, 3, 4}, Label: "hi", Blob: []byte{7}, Model: "x1", Flags: 3}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	// Readers may drop the tags
+	var subset struct {
+		Name  []byte
+		Label string
+		Flags uint8
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, []byte("probe\x00\x00\x00"), subset.Name)
+	assertEqual(t, "hi", subset.Label)
+	assertEqual(t, uint8(3), subset.Flags)
+
+	var mismatch struct{ Label int }
+	if err := tb.DecodeWithWriterSchema(data, schema, &mismatch); err == nil {
+		t.Error("Expected error resolving a string into an int")
+	}
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, deviceHeader{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	name, _ := v.Field("Name")
+	assertEqual(t, "probe", name.Scalar)
+	blob, _ := v.Field("Blob")
+	assertEqual(t, []byte{7}, blob.Scalar)
+	flags, _ := v.Field("Flags")
+	assertEqual(t, uint64(3), flags.Scalar)
+
+	old, err := tb.Schema(struct {
+		Name string `binary:"size=8"`
+	}{})
+	assertNoError(t, err)
+	cur, err := tb.Schema(struct {
+		Name string `binary:"size=16"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "size changed", issues[0].Reason)
+}
+
+func TestSizedStringCLayout(t *testing.T) {
+	// struct { uint8_t id; char name[5]; uint16_t port; }
+	type endpoint struct {
+		ID   uint8
+		Name string `binary:"size=5"`
+		Port uint16
+	}
+	tb := New(CLayout{Align: true})
+	in := endpoint{ID: 1, Name: "gw", Port: 80}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 'g', 'w', 0, 0, 0, 80, 0}, data)
+
+	var out endpoint
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
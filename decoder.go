@@ -13,7 +13,9 @@ import (
 // decoder represents a binary decoder.
 type decoder struct {
 	reader reader
-	tb     *TinyBin // Reference to the TinyBin instance for schema caching
+	tb     *TinyBin    // Reference to the TinyBin instance for schema caching
+	dict   *stringDict // String table of the message, if the payload uses one
+	order  byteOrder   // Byte order of the field being read, if tagged
 }
 
 // NewDecoder creates a binary decoder (deprecated - use TinyBin instance methods).
@@ -31,10 +33,27 @@ func (d *decoder) Decode(v any) (err error) {
 		return Err(D.Binary, "decoder", D.Required, D.Type, D.Pointer)
 	}
 
+	// Read the header and switch to the decompressed payload if needed
+	var h header
+	body := d
+	if d.tb != nil && d.tb.usesHeader() {
+		if h, err = d.readHeader(); err != nil {
+			return err
+		}
+		if body, err = d.payload(h); err != nil {
+			return err
+		}
+	}
+
+	// Migrate older message versions
+	if migrated, err := body.migrate(h.version, rv); migrated || err != nil {
+		return err
+	}
+
 	// Scan the type (this will load from cache)
 	var c Codec
-	if c, err = d.scanToCache(rv.Type()); err == nil {
-		err = c.DecodeTo(d, rv)
+	if c, err = body.scanToCache(rv.Type()); err == nil {
+		err = c.DecodeTo(body, rv)
 	}
 
 	return
@@ -88,9 +107,9 @@ func (d *decoder) ReadUint64() (out uint64, err error) {
 
 // ReadFloat32 reads a float32
 func (d *decoder) ReadFloat32() (out float32, err error) {
-	var v uint32
-	if v, err = d.ReadUint32(); err == nil {
-		out = math.Float32frombits(v)
+	var v uint64
+	if v, err = readFixed(d, 4, false); err == nil {
+		out = math.Float32frombits(uint32(v))
 	}
 	return
 }
@@ -98,7 +117,7 @@ func (d *decoder) ReadFloat32() (out float32, err error) {
 // ReadFloat64 reads a float64
 func (d *decoder) ReadFloat64() (out float64, err error) {
 	var v uint64
-	if v, err = d.ReadUint64(); err == nil {
+	if v, err = readFixed(d, 8, false); err == nil {
 		out = math.Float64frombits(v)
 	}
 	return
@@ -145,6 +164,8 @@ func (d *decoder) Reset(data []byte, tb *TinyBin) {
 		d.reader.(*sliceReader).Reset(data)
 	}
 	d.tb = tb
+	d.dict = nil
+	d.order = orderDefault
 }
 
 // scanToCache scans the type and caches it in the TinyBin instance
@@ -0,0 +1,116 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// AutoDelta lets the integer slice codecs store each slice as varint deltas
+// when that is smaller than plain varints. Pass it to New:
+// tb := tinybin.New(tinybin.AutoDelta{})
+//
+// The chosen encoding is stored in the slice length, see BitPacking, so both
+// peers must use the same configuration.
+type AutoDelta struct{}
+
+// deltaCodec encodes integer slices tagged with `binary:"delta"`: the uvarint
+// length followed by the zigzag varint difference of each element from the
+// previous one, the first element being a difference from zero.
+type deltaCodec struct {
+	signed bool
+}
+
+// newDeltaCodec returns the delta codec of an integer slice.
+func newDeltaCodec(t reflect.Type) (Codec, error) {
+	if t.Kind() == reflect.Slice {
+		switch Kind(t.Elem().Kind()) {
+		case K.Int, K.Int8, K.Int16, K.Int32, K.Int64:
+			return &deltaCodec{signed: true}, nil
+		case K.Uint, K.Uint8, K.Uint16, K.Uint32, K.Uint64:
+			return &deltaCodec{signed: false}, nil
+		}
+	}
+	return nil, Err("delta", D.Type, t.String(), D.Not, D.Supported)
+}
+
+// Encode encodes a value into the encoder.
+func (c *deltaCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	e.WriteUvarint(uint64(rv.Len()))
+	writeDeltas(e, rv, c.signed)
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *deltaCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	l, err := d.ReadUvarint()
+	if err != nil || l == 0 {
+		return err
+	}
+	rv.Set(reflect.MakeSlice(rv.Type(), int(l), int(l)))
+	return readDeltas(d, rv, c.signed)
+}
+
+// intBits returns the element as uint64 bits, so differences wrap around.
+func intBits(rv reflect.Value, signed bool) uint64 {
+	if signed {
+		return uint64(rv.Int())
+	}
+	return rv.Uint()
+}
+
+func setIntBits(rv reflect.Value, v uint64, signed bool) {
+	if signed {
+		rv.SetInt(int64(v))
+	} else {
+		rv.SetUint(v)
+	}
+}
+
+func writeDeltas(e *encoder, rv reflect.Value, signed bool) {
+	var prev uint64
+	for i := 0; i < rv.Len(); i++ {
+		v := intBits(rv.Index(i), signed)
+		e.WriteVarint(int64(v - prev))
+		prev = v
+	}
+}
+
+func readDeltas(d *decoder, rv reflect.Value, signed bool) error {
+	var prev uint64
+	for i := 0; i < rv.Len(); i++ {
+		delta, err := d.ReadVarint()
+		if err != nil {
+			return err
+		}
+		prev += uint64(delta)
+		setIntBits(rv.Index(i), prev, signed)
+	}
+	return nil
+}
+
+// varintSize returns the number of bytes WriteVarint takes for v.
+func varintSize(v int64) int {
+	x := uint64(v) << 1
+	if v < 0 {
+		x = ^x
+	}
+	return uvarintSize(x)
+}
+
+// uvarintSize returns the number of bytes WriteUvarint takes for x.
+func uvarintSize(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
+
+// isIntWire reports whether the schema is an integer written as a varint, the
+// element kinds handled by the integer slice codecs.
+func isIntWire(s *Schema) bool {
+	w := s.wire()
+	return w == wireVarint || w == wireUvarint
+}
@@ -0,0 +1,180 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+type timeSeries struct {
+	Times []int64  `binary:"delta"`
+	Seq   []uint32 `binary:"delta"`
+	Raw   []int64
+}
+
+func newTimeSeries(n int) timeSeries {
+	var s timeSeries
+	for i := 0; i < n; i++ {
+		s.Times = append(s.Times, 1700000000000+int64(i)*1000)
+		s.Seq = append(s.Seq, uint32(4000000000+i))
+		s.Raw = append(s.Raw, 1700000000000+int64(i)*1000)
+	}
+	return s
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	tb := New()
+	in := newTimeSeries(100)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var out timeSeries
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	plain, err := tb.Encode(&struct{ Times, Raw []int64 }{in.Times, in.Raw})
+	assertNoError(t, err)
+	if len(data) >= len(plain) {
+		t.Errorf("Expected delta coded payload (%d bytes) to be smaller than plain (%d bytes)", len(data), len(plain))
+	}
+}
+
+func TestDeltaExtremes(t *testing.T) {
+	type extremes struct {
+		I64 []int64  `binary:"delta"`
+		U64 []uint64 `binary:"delta"`
+		I8  []int8   `binary:"delta"`
+		U8  []uint8  `binary:"delta"`
+	}
+
+	tb := New()
+	in := extremes{
+		I64: []int64{math.MaxInt64, math.MinInt64, 0, -1, math.MaxInt64},
+		U64: []uint64{math.MaxUint64, 0, math.MaxUint64, 1},
+		I8:  []int8{-128, 127, 0},
+		U8:  []uint8{255, 0, 128},
+	}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out extremes
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestDeltaInvalidType(t *testing.T) {
+	type invalid struct {
+		Values []float64 `binary:"delta"`
+	}
+	if _, err := New().Encode(&invalid{}); err == nil {
+		t.Fatal("Expected error for delta coded float slice")
+	}
+}
+
+func TestAutoDelta(t *testing.T) {
+	tb := New(AutoDelta{})
+	in := newTimeSeries(50)
+	in.Times, in.Seq = nil, nil
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	plain, err := New().Encode(&in)
+	assertNoError(t, err)
+	if len(data) >= len(plain) {
+		t.Errorf("Expected automatic delta payload (%d bytes) to be smaller than plain (%d bytes)", len(data), len(plain))
+	}
+
+	var out timeSeries
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in.Raw, out.Raw)
+
+	// Random values stay plain: only the length is shifted
+	ids := []uint16{300, 5, 60000, 7}
+	data, err = tb.Encode(&ids)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{4 << 1, 0xAC, 0x02, 0x05, 0xE0, 0xD4, 0x03, 0x07}, data)
+
+	var decoded []uint16
+	assertNoError(t, tb.Decode(data, &decoded))
+	assertEqual(t, ids, decoded)
+}
+
+func TestDeltaSchema(t *testing.T) {
+	for _, tb := range []*TinyBin{New(), New(AutoDelta{})} {
+		schema, err := tb.Schema(timeSeries{})
+		assertNoError(t, err)
+		assertEqual(t, wireDelta, schema.Fields[0].wire())
+
+		in := newTimeSeries(10)
+		data, err := tb.Encode(&in)
+		assertNoError(t, err)
+
+		// Skipped and resolved by readers with fewer fields
+		var raw struct{ Raw []int64 }
+		assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &raw))
+		assertEqual(t, in.Raw, raw.Raw)
+
+		// Dynamic values restore absolute values
+		var file bytes.Buffer
+		w, err := tb.NewContainerWriter(&file, timeSeries{})
+		assertNoError(t, err)
+		assertNoError(t, w.Append(&in))
+		assertNoError(t, w.Close())
+
+		r, err := tb.NewContainerReader(&file)
+		assertNoError(t, err)
+		v, err := r.NextValue()
+		assertNoError(t, err)
+		for _, name := range []string{"Times", "Raw"} {
+			field, _ := v.Field(name)
+			assertEqual(t, in.Raw[9], field.Items[9].Scalar)
+		}
+		seq, _ := v.Field("Seq")
+		assertEqual(t, uint64(in.Seq[3]), seq.Items[3].Scalar)
+	}
+}
+
+func TestDeltaByteSliceSchema(t *testing.T) {
+	type levels struct {
+		Levels []uint8 `binary:"delta"`
+		Tail   string
+	}
+
+	tb := New()
+	schema, err := tb.Schema(levels{})
+	assertNoError(t, err)
+	assertEqual(t, wireDelta, schema.Fields[0].wire())
+
+	in := levels{Levels: []uint8{3, 2, 200}, Tail: "end"}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var tail struct{ Tail string }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &tail))
+	assertEqual(t, "end", tail.Tail)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, levels{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	field, _ := v.Field("Levels")
+	assertEqualInt(t, 3, len(field.Items))
+	assertEqual(t, uint64(200), field.Items[2].Scalar)
+	field, _ = v.Field("Tail")
+	assertEqual(t, "end", field.Scalar)
+
+	raw, err := tb.Schema(struct {
+		Levels []uint8
+		Tail   string
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(raw, schema)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "wire type changed", issues[0].Reason)
+}
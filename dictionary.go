@@ -0,0 +1,67 @@
+package tinybin
+
+import (
+	. "github.com/cdvelop/tinystring"
+)
+
+// StringDictionary enables the per-message string table, pass it to New:
+// tb := tinybin.New(tinybin.StringDictionary{})
+//
+// Within one Encode call the first occurrence of each string is written in
+// full and later occurrences as a varint reference, across all string fields
+// and []string elements. Both peers must use the option: instances without it
+// do not read the header and can not tell dictionary payloads from plain ones.
+type StringDictionary struct{}
+
+// maxDictStrings bounds the table, later distinct strings are written in full.
+const maxDictStrings = 1024
+
+// stringDict is the string table of a single message. Each string is written
+// as a uvarint reference: 0 is followed by a new string, n > 0 repeats the
+// string at index n-1 of the table.
+type stringDict struct {
+	strings []string
+}
+
+// writeDictString writes a string through the table of the message.
+func (e *encoder) writeDictString(s string) {
+	for i, known := range e.dict.strings {
+		if known == s {
+			e.WriteUvarint(uint64(i) + 1)
+			return
+		}
+	}
+
+	e.WriteUvarint(0)
+	e.WriteString(s)
+	if len(e.dict.strings) < maxDictStrings {
+		e.dict.strings = append(e.dict.strings, s)
+	}
+}
+
+// readDictString reads a string written by writeDictString. Repeated strings
+// share the value of their first occurrence.
+func (d *decoder) readDictString() (string, error) {
+	ref, err := d.ReadUvarint()
+	if err != nil {
+		return "", err
+	}
+	if ref > 0 {
+		if ref > uint64(len(d.dict.strings)) {
+			return "", Errf("string reference %d out of range", ref)
+		}
+		return d.dict.strings[ref-1], nil
+	}
+
+	s, err := d.ReadString()
+	if err == nil && len(d.dict.strings) < maxDictStrings {
+		d.dict.strings = append(d.dict.strings, s)
+	}
+	return s, err
+}
+
+// dictString reports whether values described by the schema go through the
+// string table of dictionary payloads.
+func (s *Schema) dictString() bool {
+	return s.Kind == K.String && !s.Marshaler && !tagOptions(s.Tag).Has("encrypt")
+}
@@ -0,0 +1,146 @@
+package tinybin
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type logEntry struct {
+	Host    string
+	Level   string
+	Message string
+	Tags    []string
+}
+
+type logBatch struct {
+	Source  string
+	Entries []logEntry
+}
+
+func newLogBatch(n int) logBatch {
+	b := logBatch{Source: "gateway-eu-west-1"}
+	for i := 0; i < n; i++ {
+		b.Entries = append(b.Entries, logEntry{
+			Host:    []string{"gateway-eu-west-1", "gateway-eu-west-2"}[i%2],
+			Level:   []string{"info", "warning", "error"}[i%3],
+			Message: strings.Repeat("x", i%5),
+			Tags:    []string{"production", "edge"},
+		})
+	}
+	return b
+}
+
+func TestStringDictionaryRoundTrip(t *testing.T) {
+	tb := New(StringDictionary{})
+	in := newLogBatch(200)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	if data[0]&headerStrings == 0 {
+		t.Fatal("Expected the string table flag in the header")
+	}
+
+	plain, err := New().Encode(&in)
+	assertNoError(t, err)
+	if len(data)*3 >= len(plain) {
+		t.Errorf("Expected dictionary payload (%d bytes) to be far smaller than plain (%d bytes)", len(data), len(plain))
+	}
+
+	var out logBatch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// Repeated strings share the decoded value
+	if unsafe.StringData(out.Entries[0].Host) != unsafe.StringData(out.Entries[2].Host) {
+		t.Error("Expected repeated strings to reuse the first decoded value")
+	}
+
+	// Every Encode call starts a new table
+	again, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, data, again)
+}
+
+func TestStringDictionaryWithOtherOptions(t *testing.T) {
+	tb := New(StringDictionary{}, Compression{Compressor: FlateCompressor{}}, ChecksumCastagnoli)
+	in := newLogBatch(50)
+
+	var stream bytes.Buffer
+	assertNoError(t, tb.EncodeTo(&in, &stream))
+	assertNoError(t, tb.EncodeTo(&basicStruct{Name: "next"}, &stream))
+
+	r := bufio.NewReader(&stream)
+	var out logBatch
+	assertNoError(t, tb.DecodeFrom(r, &out))
+	assertEqual(t, in, out)
+	var next basicStruct
+	assertNoError(t, tb.DecodeFrom(r, &next))
+	assertEqual(t, "next", next.Name)
+}
+
+func TestStringDictionaryLimit(t *testing.T) {
+	tb := New(StringDictionary{})
+	var in []string
+	for i := 0; i < maxDictStrings+10; i++ {
+		in = append(in, strings.Repeat("a", i%40)+string(rune('a'+i%26))+strings.Repeat("b", i/26))
+	}
+	in = append(in, in...)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out []string
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestStringDictionaryInvalidReference(t *testing.T) {
+	tb := New(StringDictionary{})
+	data := []byte{headerStrings, 1, 5} // one string referencing entry 5
+	var out []string
+	if err := tb.Decode(data, &out); err == nil {
+		t.Fatal("Expected error for out of range string reference")
+	}
+}
+
+func TestStringDictionarySchema(t *testing.T) {
+	tb := New(StringDictionary{})
+	schema, err := tb.Schema(logBatch{})
+	assertNoError(t, err)
+
+	in := newLogBatch(10)
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	// Skipped fields still register their strings in the table
+	var hostsOnly struct {
+		Entries []struct {
+			Level []byte
+			Tags  []string
+		}
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &hostsOnly))
+	assertEqualInt(t, 10, len(hostsOnly.Entries))
+	assertEqualBytes(t, []byte("error"), hostsOnly.Entries[5].Level)
+	assertEqual(t, in.Entries[9].Tags, hostsOnly.Entries[9].Tags)
+}
+
+func TestStringDictionaryMismatchedDecoder(t *testing.T) {
+	in := newLogBatch(4)
+
+	// A dictionary instance refuses payloads written without the option
+	plain, err := New(Compression{Compressor: FlateCompressor{}}).Encode(&in)
+	assertNoError(t, err)
+	var out logBatch
+	if err := New(StringDictionary{}, Compression{Compressor: FlateCompressor{}}).Decode(plain, &out); err == nil {
+		t.Error("Expected error decoding a payload without string table")
+	}
+
+	// Any instance reading headers decodes dictionary payloads
+	data, err := New(StringDictionary{}).Encode(&in)
+	assertNoError(t, err)
+	assertNoError(t, New(Compression{Compressor: FlateCompressor{}}).Decode(data, &out))
+	assertEqual(t, in, out)
+}
@@ -12,10 +12,13 @@ import (
 
 // encoder represents a binary encoder.
 type encoder struct {
-	scratch [10]byte
-	tb      *TinyBin // Reference to the TinyBin instance for schema caching
-	out     io.Writer
-	err     error
+	scratch   [10]byte
+	canonical bool        // Normalize floats for a deterministic output
+	order     byteOrder   // Byte order of the field being written, if tagged
+	dict      *stringDict // String table of the message, if enabled
+	tb        *TinyBin    // Reference to the TinyBin instance for schema caching
+	out       io.Writer
+	err       error
 }
 
 // NewEncoder creates a new encoder (deprecated - use TinyBin instance methods).
@@ -30,6 +33,9 @@ func (e *encoder) Reset(out io.Writer, tb *TinyBin) {
 	e.out = out
 	e.err = nil
 	e.tb = tb
+	e.canonical = tb != nil && tb.canonical
+	e.order = orderDefault
+	e.dict = nil
 }
 
 // Buffer returns the underlying writer.
@@ -52,6 +58,11 @@ func (e *encoder) Encode(v any) (err error) {
 		return
 	}
 
+	// Payloads of instances using headers describe how they were produced
+	if e.tb != nil && e.tb.usesHeader() {
+		return e.encodeWithHeader(c, rv)
+	}
+
 	// Encode the value
 	if err = c.EncodeTo(e, rv); err == nil {
 		err = e.err
@@ -126,12 +137,18 @@ func (e *encoder) WriteUint64(v uint64) {
 
 // WriteFloat32 a 32-bit floating point number
 func (e *encoder) WriteFloat32(v float32) {
-	e.WriteUint32(math.Float32bits(v))
+	if e.canonical {
+		v = canonicalFloat32(v)
+	}
+	writeFixed(e, uint64(math.Float32bits(v)), 4)
 }
 
 // WriteFloat64 a 64-bit floating point number
 func (e *encoder) WriteFloat64(v float64) {
-	e.WriteUint64(math.Float64bits(v))
+	if e.canonical {
+		v = canonicalFloat64(v)
+	}
+	writeFixed(e, math.Float64bits(v), 8)
 }
 
 // WriteBool writes a single boolean value into the buffer
@@ -130,8 +130,8 @@ func TestBinaryEncodeStruct(t *testing.T) {
 func TestEncoderSizeOf(t *testing.T) {
 	var e encoder
 	size := int(unsafe.Sizeof(e))
-	if size != 56 {
-		t.Errorf("Expected %v, got %v", 56, size)
+	if size != 64 {
+		t.Errorf("Expected %v, got %v", 64, size)
 	}
 }
 
@@ -0,0 +1,147 @@
+package tinybin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// KeyProvider supplies the AEADs sealing the fields tagged `binary:"encrypt"`.
+// Key ids are stored next to each encrypted field so keys can be rotated while
+// older payloads stay readable.
+type KeyProvider interface {
+	// SealingKey returns the id and the AEAD used to encrypt new values.
+	SealingKey() (id uint32, aead cipher.AEAD, err error)
+	// OpeningKey returns the AEAD for a key id read from an encrypted field.
+	OpeningKey(id uint32) (cipher.AEAD, error)
+}
+
+// NewAESGCMKey returns a KeyProvider sealing every field with AES-GCM under a
+// single key of 16, 24 or 32 bytes, stored with key id 0.
+func NewAESGCMKey(key []byte) (KeyProvider, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKey{aead: aead}, nil
+}
+
+// staticKey is a KeyProvider holding a single AEAD.
+type staticKey struct {
+	aead cipher.AEAD
+}
+
+func (k *staticKey) SealingKey() (uint32, cipher.AEAD, error) {
+	return 0, k.aead, nil
+}
+
+func (k *staticKey) OpeningKey(id uint32) (cipher.AEAD, error) {
+	if id != 0 {
+		return nil, Errf("encryption key %d not found", id)
+	}
+	return k.aead, nil
+}
+
+// ------------------------------------------------------------------------------
+
+var errNoKeyProvider = Err("encrypt", "key provider", D.Missing)
+
+// encryptedCodec seals the encoded bytes of a field. On the wire the field is
+// the uvarint length of the sealed bytes followed by the uvarint key id, the
+// nonce and the ciphertext. The struct type, the field name and the field type
+// are authenticated as additional data so sealed values can not be swapped
+// between fields or messages.
+type encryptedCodec struct {
+	elemCodec Codec  // The codec of the plain value
+	name      string // The name of the field
+	aad       string // The additional data, e.g. "main.Patient.SSN string"
+}
+
+// Encode encodes a value into the encoder.
+func (c *encryptedCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	if e.canonical {
+		return Err("encrypted field", c.name, D.Not, D.Supported, "canonical")
+	}
+	if e.tb == nil || e.tb.keys == nil {
+		return errNoKeyProvider
+	}
+	id, aead, err := e.tb.keys.SealingKey()
+	if err != nil {
+		return err
+	}
+
+	// Encode the plain value on its own
+	var plain bytes.Buffer
+	inner := &encoder{out: &plain, tb: e.tb, order: e.order}
+	if err = c.elemCodec.EncodeTo(inner, rv); err != nil {
+		return err
+	}
+	if inner.err != nil {
+		return inner.err
+	}
+
+	sealed := make([]byte, putUvarint(make([]byte, 10), uint64(id)), 10+aead.NonceSize()+plain.Len()+aead.Overhead())
+	putUvarint(sealed, uint64(id))
+	nonce := sealed[len(sealed) : len(sealed)+aead.NonceSize()]
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed = aead.Seal(sealed[:len(sealed)+len(nonce)], nonce, plain.Bytes(), ToBytes(c.aad))
+
+	e.WriteUvarint(uint64(len(sealed)))
+	e.Write(sealed)
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *encryptedCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if d.tb == nil || d.tb.keys == nil {
+		return errNoKeyProvider
+	}
+
+	sealed, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+
+	r := newSliceReader(sealed)
+	id, err := r.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	aead, err := d.tb.keys.OpeningKey(uint32(id))
+	if err != nil {
+		return err
+	}
+	nonce, err := r.Slice(aead.NonceSize())
+	if err != nil {
+		return err
+	}
+
+	plain, err := aead.Open(nil, nonce, sealed[len(sealed)-r.Len():], ToBytes(c.aad))
+	if err != nil {
+		return Err("encrypted field", c.name, D.Invalid)
+	}
+	return c.elemCodec.DecodeTo(&decoder{reader: newSliceReader(plain), tb: d.tb, order: d.order}, rv)
+}
+
+// resolveEncrypted resolves a sealed field of the writer struct type owner as
+// the untagged field, opened with the additional data the writer sealed it with.
+func (tb *TinyBin) resolveEncrypted(owner string, w *Schema, field reflect.StructField) (fieldCodec, error) {
+	plain := *w
+	plain.Tag = tagOptions(w.Tag).without("encrypt")
+	codec, err := tb.resolve(&plain, field.Type)
+	if err != nil {
+		return fieldCodec{}, err
+	}
+	aad := owner + "." + w.Name + " " + w.Type
+	return fieldCodec{Index: field.Index[0], Codec: &encryptedCodec{elemCodec: codec, name: w.Name, aad: aad}}, nil
+}
@@ -0,0 +1,170 @@
+package tinybin
+
+import (
+	"bytes"
+	"testing"
+)
+
+type encryptedStruct struct {
+	ID     uint32
+	SSN    string  `binary:"encrypt"`
+	Card   []byte  `binary:"encrypt"`
+	Scores []int64 `binary:"encrypt"`
+	Note   *string `binary:"encrypt"`
+	Tags   []string
+}
+
+func newEncryptedTinyBin(t *testing.T, key byte) *TinyBin {
+	keys, err := NewAESGCMKey(bytes.Repeat([]byte{key}, 32))
+	assertNoError(t, err)
+	return New(keys)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	note := "vip"
+	in := encryptedStruct{ID: 7, SSN: "123-45-6789", Card: []byte("4111111111111111"), Scores: []int64{-1, 2}, Note: &note, Tags: []string{"a"}}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	if bytes.Contains(data, []byte(in.SSN)) || bytes.Contains(data, in.Card) {
+		t.Fatal("Expected encrypted fields not to appear in clear text")
+	}
+
+	var out encryptedStruct
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// Every encoding uses a fresh nonce
+	again, err := tb.Encode(&in)
+	assertNoError(t, err)
+	if bytes.Equal(data, again) {
+		t.Error("Expected different ciphertexts for the same value")
+	}
+}
+
+func TestEncryptWrongKey(t *testing.T) {
+	data, err := newEncryptedTinyBin(t, 1).Encode(&encryptedStruct{SSN: "secret"})
+	assertNoError(t, err)
+
+	var out encryptedStruct
+	if err := newEncryptedTinyBin(t, 2).Decode(data, &out); err == nil {
+		t.Fatal("Expected error decoding with the wrong key")
+	}
+}
+
+func TestEncryptTampered(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	data, err := tb.Encode(&encryptedStruct{SSN: "secret"})
+	assertNoError(t, err)
+
+	data[len(data)/2] ^= 0xFF
+	var out encryptedStruct
+	if err := tb.Decode(data, &out); err == nil {
+		t.Fatal("Expected error decoding a tampered payload")
+	}
+}
+
+func TestEncryptWithoutKeys(t *testing.T) {
+	if _, err := New().Encode(&encryptedStruct{SSN: "secret"}); err != errNoKeyProvider {
+		t.Fatalf("Expected errNoKeyProvider, got %v", err)
+	}
+}
+
+func TestEncryptSchema(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	schema, err := tb.Schema(encryptedStruct{})
+	assertNoError(t, err)
+	assertEqual(t, wireBytes, schema.Fields[1].wire())
+
+	// Encrypted fields can be skipped without the key
+	data, err := tb.Encode(&encryptedStruct{ID: 3, SSN: "secret", Tags: []string{"x"}})
+	assertNoError(t, err)
+	var out struct {
+		ID   uint32
+		Tags []string
+	}
+	assertNoError(t, New().DecodeWithWriterSchema(data, schema, &out))
+	assertEqual(t, uint32(3), out.ID)
+	assertEqual(t, []string{"x"}, out.Tags)
+}
+
+func TestTagOptions(t *testing.T) {
+	opts := tagOptions("encrypt,len=Count,if=Kind==3")
+	assertEqual(t, true, opts.Has("encrypt"))
+	assertEqual(t, false, opts.Has("fixed"))
+
+	v, ok := opts.Get("len")
+	assertEqual(t, true, ok)
+	assertEqual(t, "Count", v)
+
+	v, _ = opts.Get("if")
+	assertEqual(t, "Kind==3", v)
+}
+
+type patientRecord struct {
+	SSN string `binary:"encrypt"`
+}
+
+type employeeRecord struct {
+	SSN string `binary:"encrypt"`
+}
+
+func TestEncryptBindsFieldPath(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	data, err := tb.Encode(&patientRecord{SSN: "secret"})
+	assertNoError(t, err)
+
+	var patient patientRecord
+	assertNoError(t, tb.Decode(data, &patient))
+	assertEqual(t, "secret", patient.SSN)
+
+	// The same field name in another message does not open the value
+	var employee employeeRecord
+	if err := tb.Decode(data, &employee); err == nil {
+		t.Error("Expected error decoding a value sealed for another type")
+	}
+}
+
+func TestEncryptCompatibility(t *testing.T) {
+	tb := New()
+	old, err := tb.Schema(struct{ SSN string }{})
+	assertNoError(t, err)
+	cur, err := tb.Schema(patientRecord{})
+	assertNoError(t, err)
+
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "encryption changed", issues[0].Reason)
+	assertEqualInt(t, 1, len(CheckCompatibility(cur, old)))
+}
+
+type sealedV1 struct {
+	ID    uint32
+	SSN   string `binary:"encrypt"`
+	Score int32  `binary:"encrypt"`
+}
+
+type sealedV2 struct {
+	ID    uint64
+	SSN   []byte `binary:"encrypt"`
+	Score int64
+}
+
+func TestEncryptResolvesChangedReader(t *testing.T) {
+	tb := newEncryptedTinyBin(t, 1)
+	schema, err := tb.Schema(sealedV1{})
+	assertNoError(t, err)
+	data, err := tb.Encode(&sealedV1{ID: 1, SSN: "secret", Score: -7})
+	assertNoError(t, err)
+
+	// Sealed fields are opened with the writer type and widened like plain ones
+	var out sealedV2
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &out))
+	assertEqual(t, sealedV2{ID: 1, SSN: []byte("secret"), Score: -7}, out)
+
+	var wrong struct{ SSN int }
+	if err := tb.DecodeWithWriterSchema(data, schema, &wrong); err == nil {
+		t.Error("Expected error resolving a sealed string into an int")
+	}
+}
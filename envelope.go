@@ -0,0 +1,172 @@
+package tinybin
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// messageEntry associates a message id with the registered Go type.
+type messageEntry struct {
+	ID   uint32
+	Type reflect.Type
+}
+
+// RegisterMessage associates a message id with the type of the sample so that
+// values can be sent over a multiplexed stream and decoded without knowing
+// their type in advance.
+func (tb *TinyBin) RegisterMessage(id uint32, sample any) error {
+	rv := reflect.Indirect(reflect.ValueOf(sample))
+	if !rv.IsValid() {
+		return Err("RegisterMessage", D.Type, D.Nil)
+	}
+	typ := rv.Type()
+
+	// Make sure the type is supported before accepting it
+	if _, err := tb.scanToCache(typ); err != nil {
+		return err
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	for _, entry := range tb.messages {
+		if entry.ID == id || entry.Type == typ {
+			return Errf("message id %d or type %s already registered", id, typ.String())
+		}
+	}
+
+	tb.messages = append(tb.messages, messageEntry{ID: id, Type: typ})
+	return nil
+}
+
+// EncodeMessage encodes a registered message as an envelope: the uvarint
+// message id, the uvarint payload length and the payload produced by Encode.
+func (tb *TinyBin) EncodeMessage(v any) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := tb.EncodeMessageTo(v, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// EncodeMessageTo writes the envelope of a registered message to dst.
+func (tb *TinyBin) EncodeMessageTo(v any, dst io.Writer) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return Errf("cannot encode nil value")
+	}
+	id, ok := tb.messageID(rv.Type())
+	if !ok {
+		return Err("message", D.Type, rv.Type().String(), D.Not, "registered")
+	}
+
+	payload, err := tb.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	e := tb.encoders.Get().(*encoder)
+	e.Reset(dst, tb)
+	e.WriteUvarint(uint64(id))
+	e.WriteUvarint(uint64(len(payload)))
+	e.Write(payload)
+	err = e.err
+	tb.encoders.Put(e)
+	return err
+}
+
+// DecodeMessage decodes an envelope written by EncodeMessage and returns a
+// value of the type registered for its id.
+func (tb *TinyBin) DecodeMessage(data []byte) (any, error) {
+	id, payload, err := splitMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	return tb.decodeMessage(id, payload)
+}
+
+// ReadMessage reads the next envelope from a stream and returns a value of the
+// type registered for its id. Pass a reader implementing io.ByteReader (such as
+// a bufio.Reader) when reading several messages from the same stream.
+func (tb *TinyBin) ReadMessage(r io.Reader) (any, error) {
+	rdr := newReader(r)
+	id, err := rdr.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	l, err := rdr.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > tb.messageLimit() {
+		return nil, ErrMessageSize
+	}
+
+	payload := make([]byte, int(l))
+	if _, err = io.ReadFull(rdr, payload); err != nil {
+		return nil, err
+	}
+	return tb.decodeMessage(uint32(id), payload)
+}
+
+// PeekMessageID returns the message id of an envelope without decoding it.
+func PeekMessageID(data []byte) (uint32, error) {
+	id, err := newSliceReader(data).ReadUvarint()
+	return uint32(id), err
+}
+
+// splitMessage separates the message id from the payload of an envelope.
+func splitMessage(data []byte) (id uint32, payload []byte, err error) {
+	r := newSliceReader(data)
+	var v, l uint64
+	if v, err = r.ReadUvarint(); err != nil {
+		return 0, nil, err
+	}
+	if l, err = r.ReadUvarint(); err != nil {
+		return 0, nil, err
+	}
+	if l > uint64(r.Len()) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	payload, err = r.Slice(int(l))
+	return uint32(v), payload, err
+}
+
+func (tb *TinyBin) decodeMessage(id uint32, payload []byte) (any, error) {
+	typ, ok := tb.messageType(id)
+	if !ok {
+		return nil, Errf("message id %d not registered", id)
+	}
+
+	ptr := reflect.New(typ)
+	if err := tb.Decode(payload, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// messageID returns the id registered for a type.
+func (tb *TinyBin) messageID(t reflect.Type) (uint32, bool) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	for _, entry := range tb.messages {
+		if entry.Type == t {
+			return entry.ID, true
+		}
+	}
+	return 0, false
+}
+
+// messageType returns the type registered for a message id.
+func (tb *TinyBin) messageType(id uint32) (reflect.Type, bool) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	for _, entry := range tb.messages {
+		if entry.ID == id {
+			return entry.Type, true
+		}
+	}
+	return nil, false
+}
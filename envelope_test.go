@@ -0,0 +1,81 @@
+package tinybin
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+type pingMessage struct {
+	Seq uint32
+}
+
+type telemetryMessage struct {
+	Device string
+	Values []int32
+}
+
+func newMessageInstance(t *testing.T) *TinyBin {
+	t.Helper()
+	tb := New()
+	assertNoError(t, tb.RegisterMessage(1, pingMessage{}))
+	assertNoError(t, tb.RegisterMessage(7, &telemetryMessage{}))
+	return tb
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	tb := newMessageInstance(t)
+	in := telemetryMessage{Device: "node-3", Values: []int32{-1, 0, 12}}
+
+	data, err := tb.EncodeMessage(&in)
+	assertNoError(t, err)
+
+	id, err := PeekMessageID(data)
+	assertNoError(t, err)
+	assertEqual(t, uint32(7), id)
+
+	out, err := tb.DecodeMessage(data)
+	assertNoError(t, err)
+	assertEqual(t, in, out)
+}
+
+func TestEnvelopeStream(t *testing.T) {
+	tb := newMessageInstance(t)
+
+	var stream bytes.Buffer
+	assertNoError(t, tb.EncodeMessageTo(pingMessage{Seq: 1}, &stream))
+	assertNoError(t, tb.EncodeMessageTo(&telemetryMessage{Device: "a"}, &stream))
+	assertNoError(t, tb.EncodeMessageTo(pingMessage{Seq: 2}, &stream))
+
+	r := bufio.NewReader(&stream)
+	var got []any
+	for i := 0; i < 3; i++ {
+		msg, err := tb.ReadMessage(r)
+		assertNoError(t, err)
+		got = append(got, msg)
+	}
+	assertEqual(t, []any{pingMessage{Seq: 1}, telemetryMessage{Device: "a"}, pingMessage{Seq: 2}}, got)
+}
+
+func TestEnvelopeErrors(t *testing.T) {
+	tb := newMessageInstance(t)
+
+	if err := tb.RegisterMessage(1, basicStruct{}); err == nil {
+		t.Error("Expected error for duplicated id")
+	}
+	if _, err := tb.EncodeMessage(&basicStruct{}); err == nil {
+		t.Error("Expected error for unregistered type")
+	}
+	if _, err := tb.DecodeMessage([]byte{9, 0}); err == nil {
+		t.Error("Expected error for unknown id")
+	}
+	if _, err := tb.DecodeMessage([]byte{1, 5, 1}); err == nil {
+		t.Error("Expected error for truncated payload")
+	}
+
+	// A corrupted length is rejected before the payload is allocated
+	huge := []byte{1, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}
+	if _, err := tb.ReadMessage(bytes.NewReader(huge)); err != ErrMessageSize {
+		t.Errorf("Expected ErrMessageSize, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// FixedWidth makes every integer take the natural size of its kind in little
+// endian bytes instead of a varint: one byte for int8 and uint8, two for int16
+// and uint16, four for int32 and uint32 and eight for the others. Pass it to
+// New: tb := tinybin.New(tinybin.FixedWidth{})
+//
+// Slice lengths stay uvarints and integer slices are not delta coded or bit
+// packed, so both peers must use the same configuration.
+type FixedWidth struct{}
+
+// fixedCodec encodes an integer field tagged with `binary:"fixed"`, `fixed16`,
+// `fixed32` or `fixed64` in a fixed number of bytes, little endian unless the
+// instance or the field selects big endian.
+type fixedCodec struct {
+	width  int // The number of bytes, 1, 2, 4 or 8
+	signed bool
+}
+
+// newFixedCodec returns the fixed codec of an integer field, or nil when the
+// field has no fixed tag.
+func newFixedCodec(t reflect.Type, opts tagOptions) (Codec, error) {
+	k := Kind(t.Kind())
+	width := fixedTag(opts, k)
+	if width == 0 {
+		if opts.Has("fixed") || opts.Has("fixed16") || opts.Has("fixed32") || opts.Has("fixed64") {
+			return nil, Err("fixed", D.Type, t.String(), D.Not, D.Supported)
+		}
+		return nil, nil
+	}
+	return &fixedCodec{width: width, signed: signedKind(k)}, nil
+}
+
+// Encode encodes a value into the encoder.
+func (c *fixedCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	bits := uint(c.width * 8)
+	if c.signed {
+		v := rv.Int()
+		if bits < 64 && (v < -1<<(bits-1) || v >= 1<<(bits-1)) {
+			return Errf("value %d overflows fixed%d", v, bits)
+		}
+		writeFixed(e, uint64(v), c.width)
+		return nil
+	}
+
+	v := rv.Uint()
+	if bits < 64 && v >= 1<<bits {
+		return Errf("value %d overflows fixed%d", v, bits)
+	}
+	writeFixed(e, v, c.width)
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *fixedCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	v, err := readFixed(d, c.width, c.signed)
+	if err != nil {
+		return err
+	}
+
+	if c.signed {
+		if rv.OverflowInt(int64(v)) {
+			return Errf("value %d overflows %s", int64(v), rv.Type().String())
+		}
+		rv.SetInt(int64(v))
+		return nil
+	}
+	if rv.OverflowUint(v) {
+		return Errf("value %d overflows %s", v, rv.Type().String())
+	}
+	rv.SetUint(v)
+	return nil
+}
+
+// fixedTag returns the number of bytes selected by the fixed tag options for an
+// integer of kind k, or 0 when there is none or k is not an integer kind.
+func fixedTag(opts tagOptions, k Kind) int {
+	if !signedKind(k) && !unsignedKind(k) {
+		return 0
+	}
+	switch {
+	case opts.Has("fixed"):
+		return fixedSize(k)
+	case opts.Has("fixed16"):
+		return 2
+	case opts.Has("fixed32"):
+		return 4
+	case opts.Has("fixed64"):
+		return 8
+	}
+	return 0
+}
+
+// fixedSize returns the natural size in bytes of an integer kind.
+func fixedSize(k Kind) int {
+	switch k {
+	case K.Int8, K.Uint8:
+		return 1
+	case K.Int16, K.Uint16:
+		return 2
+	case K.Int32, K.Uint32:
+		return 4
+	}
+	return 8
+}
+
+func signedKind(k Kind) bool {
+	switch k {
+	case K.Int, K.Int8, K.Int16, K.Int32, K.Int64:
+		return true
+	}
+	return false
+}
+
+func unsignedKind(k Kind) bool {
+	switch k {
+	case K.Uint, K.Uint8, K.Uint16, K.Uint32, K.Uint64:
+		return true
+	}
+	return false
+}
+
+// writeFixed writes the low width bytes of v in the byte order of the encoder.
+func writeFixed(e *encoder, v uint64, width int) {
+	if e.bigEndian() {
+		for i := 0; i < width; i++ {
+			e.scratch[i] = byte(v >> (8 * (width - 1 - i)))
+		}
+		e.Write(e.scratch[:width])
+		return
+	}
+
+	switch width {
+	case 1:
+		e.scratch[0] = byte(v)
+		e.Write(e.scratch[:1])
+	case 2:
+		e.WriteUint16(uint16(v))
+	case 4:
+		e.WriteUint32(uint32(v))
+	default:
+		e.WriteUint64(v)
+	}
+}
+
+// readFixed reads width bytes in the byte order of the decoder, sign extending
+// signed values.
+func readFixed(d *decoder, width int, signed bool) (v uint64, err error) {
+	switch {
+	case d.bigEndian():
+		var b []byte
+		b, err = d.Slice(width)
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+	case width == 1:
+		var b byte
+		b, err = d.reader.ReadByte()
+		v = uint64(b)
+	case width == 2:
+		var x uint16
+		x, err = d.ReadUint16()
+		v = uint64(x)
+	case width == 4:
+		var x uint32
+		x, err = d.ReadUint32()
+		v = uint64(x)
+	default:
+		v, err = d.ReadUint64()
+	}
+	if signed && width < 8 {
+		shift := uint(64 - width*8)
+		v = uint64(int64(v<<shift) >> shift)
+	}
+	return v, err
+}
+
+// readFixedScalar reads a fixed width integer as an int64 or an uint64.
+func readFixedScalar(d *decoder, width int, signed bool) (any, error) {
+	v, err := readFixed(d, width, signed)
+	if signed {
+		return int64(v), err
+	}
+	return v, err
+}
+
+// fixedInts reports whether integers take the natural size of their kind.
+func (tb *TinyBin) fixedInts() bool {
+	return tb != nil && tb.fixedWidth
+}
+
+// fixedInts reports whether the decoder reads integers written by an instance
+// with FixedWidth.
+func (d *decoder) fixedInts() bool {
+	return d.tb.fixedInts()
+}
+
+// encodeFixedSlice writes an integer slice of an instance with FixedWidth.
+func encodeFixedSlice(e *encoder, rv reflect.Value, signed bool) {
+	l := rv.Len()
+	width := fixedSize(Kind(rv.Type().Elem().Kind()))
+	e.WriteUvarint(uint64(l))
+	for i := 0; i < l; i++ {
+		writeFixed(e, intBits(rv.Index(i), signed), width)
+	}
+}
+
+// decodeFixedSlice reads an integer slice written by encodeFixedSlice.
+func decodeFixedSlice(d *decoder, rv reflect.Value, signed bool) error {
+	l, err := d.ReadUvarint()
+	if err != nil || l == 0 {
+		return err
+	}
+
+	width := fixedSize(Kind(rv.Type().Elem().Kind()))
+	rv.Set(reflect.MakeSlice(rv.Type(), int(l), int(l)))
+	for i := 0; i < int(l); i++ {
+		v, err := readFixed(d, width, signed)
+		if err != nil {
+			return err
+		}
+		setIntBits(rv.Index(i), v, signed)
+	}
+	return nil
+}
+
+// fixedWidth returns the width in bytes of an integer field tagged as fixed, or
+// 0 for other fields.
+func (s *Schema) fixedWidth() int {
+	return fixedTag(tagOptions(s.Tag), s.Kind)
+}
+
+// resolveFixed returns the codec reading a fixed width integer of the writer
+// into an integer of the same signedness.
+func resolveFixed(w *Schema, t reflect.Type) (Codec, error) {
+	k := Kind(t.Kind())
+	if signedKind(w.Kind) && signedKind(k) || unsignedKind(w.Kind) && unsignedKind(k) {
+		return &fixedCodec{width: w.fixedWidth(), signed: signedKind(k)}, nil
+	}
+	return nil, Err(D.Field, w.Name, D.Not, D.Assignable, "from", w.Tag)
+}
@@ -0,0 +1,181 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+type radioFrame struct {
+	ID     uint64 `binary:"fixed"`
+	Hash   uint32 `binary:"fixed"`
+	Offset int    `binary:"fixed16"`
+	Seq    uint16 `binary:"fixed32"`
+	Level  int8   `binary:"fixed"`
+	Count  uint32
+}
+
+func TestFixedTagLayout(t *testing.T) {
+	tb := New()
+	in := radioFrame{ID: 0x0102030405060708, Hash: 0xDEADBEEF, Offset: -2, Seq: 0x1234, Level: -1, Count: 300}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // ID
+		0xEF, 0xBE, 0xAD, 0xDE, // Hash
+		0xFE, 0xFF, // Offset
+		0x34, 0x12, 0x00, 0x00, // Seq
+		0xFF,       // Level
+		0xAC, 0x02, // Count as a varint
+	}, data)
+
+	var out radioFrame
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestFixedTagRange(t *testing.T) {
+	tb := New()
+	for _, in := range []radioFrame{{Offset: math.MaxInt16 + 1}, {Offset: math.MinInt16 - 1}} {
+		if _, err := tb.Encode(&in); err == nil {
+			t.Errorf("Expected range error encoding %+v", in)
+		}
+	}
+
+	in := radioFrame{Offset: math.MinInt16, Seq: math.MaxUint16}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out radioFrame
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// Wider values than the target are rejected on decode
+	var narrow struct {
+		V uint8 `binary:"fixed16"`
+	}
+	if err := tb.Decode([]byte{0x00, 0x01}, &narrow); err == nil {
+		t.Error("Expected overflow decoding 256 into an uint8")
+	}
+
+	if _, err := tb.Encode(&struct {
+		Name string `binary:"fixed"`
+	}{}); err == nil {
+		t.Error("Expected error for a fixed string")
+	}
+}
+
+func TestFixedWidthInstance(t *testing.T) {
+	tb := New(FixedWidth{}, AutoDelta{})
+	type sample struct {
+		A int8
+		B uint16
+		C int
+		D []int32
+		E [2]uint64
+		F *int16
+	}
+
+	f := int16(-7)
+	in := sample{A: -1, B: 2, C: -3, D: []int32{1, -1}, E: [2]uint64{5, math.MaxUint64}, F: &f}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualInt(t, 1+2+8+(1+4*2)+8*2+(1+2), len(data))
+
+	var out sample
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// Payload sizes do not depend on the values
+	zero, err := tb.Encode(&sample{D: []int32{0, 0}, F: new(int16)})
+	assertNoError(t, err)
+	assertEqualInt(t, len(data), len(zero))
+}
+
+func TestFixedSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(radioFrame{})
+	assertNoError(t, err)
+	assertEqual(t, wireFixed, schema.Fields[0].wire())
+
+	in := radioFrame{ID: 7, Hash: 9, Offset: -300, Seq: 11, Level: 4, Count: 13}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	// Readers may drop the tag and change the integer size
+	var subset struct {
+		Offset int32
+		Count  uint64
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, int32(-300), subset.Offset)
+	assertEqual(t, uint64(13), subset.Count)
+
+	var mismatch struct{ Hash int64 }
+	if err := tb.DecodeWithWriterSchema(data, schema, &mismatch); err == nil {
+		t.Error("Expected error resolving an unsigned fixed field into a signed field")
+	}
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, radioFrame{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	offset, _ := v.Field("Offset")
+	assertEqual(t, int64(-300), offset.Scalar)
+	count, _ := v.Field("Count")
+	assertEqual(t, uint64(13), count.Scalar)
+
+	old, err := tb.Schema(struct {
+		Seq uint16 `binary:"fixed32"`
+	}{})
+	assertNoError(t, err)
+	cur, err := tb.Schema(struct {
+		Seq uint16 `binary:"fixed"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "fixed width changed", issues[0].Reason)
+}
+
+func TestFixedWidthInstanceSchema(t *testing.T) {
+	tb := New(FixedWidth{})
+	type reading struct {
+		Time  int64
+		Steps []uint16
+		Temp  int8
+	}
+	schema, err := tb.Schema(reading{})
+	assertNoError(t, err)
+
+	in := reading{Time: -1, Steps: []uint16{1, 500}, Temp: -40}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var subset struct {
+		Steps []uint32
+		Temp  int16
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, []uint32{1, 500}, subset.Steps)
+	assertEqual(t, int16(-40), subset.Temp)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, reading{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	steps, _ := v.Field("Steps")
+	assertEqual(t, uint64(500), steps.Items[1].Scalar)
+	temp, _ := v.Field("Temp")
+	assertEqual(t, int64(-40), temp.Scalar)
+}
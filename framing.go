@@ -0,0 +1,239 @@
+package tinybin
+
+import (
+	"bufio"
+	"hash/crc32"
+	"io"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Framing selects how frames are delimited on byte-oriented links.
+type Framing uint8
+
+const (
+	FramingCOBS Framing = iota // Consistent Overhead Byte Stuffing, frames end with 0x00
+	FramingSLIP                // RFC 1055 SLIP, frames end with 0xC0
+)
+
+// SLIP special bytes
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+// defaultMaxFrameSize bounds the bytes buffered while looking for a delimiter.
+const defaultMaxFrameSize = 64 * 1024
+
+// ErrFrame is returned by FrameReader for frames that are malformed or exceed
+// the maximum size. Reading can continue: the next call resynchronizes on the
+// following delimiter.
+var ErrFrame = Err(D.Binary, "frame", D.Invalid)
+
+// ------------------------------------------------------------------------------
+
+// FrameWriter writes encoded values as self-delimited frames carrying a
+// trailing CRC, suitable for UARTs and other links that may drop bytes.
+type FrameWriter struct {
+	tb      *TinyBin
+	out     io.Writer
+	framing Framing
+	frame   []byte // reused frame buffer
+}
+
+// NewFrameWriter creates a frame writer encoding with this TinyBin instance.
+func (tb *TinyBin) NewFrameWriter(w io.Writer, framing Framing) *FrameWriter {
+	return &FrameWriter{tb: tb, out: w, framing: framing}
+}
+
+// WriteFrame encodes the value and writes it as a single frame.
+func (w *FrameWriter) WriteFrame(v any) error {
+	payload, err := w.tb.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	// The CRC travels inside the frame, so it is stuffed like the payload
+	crc := crc32.Checksum(payload, w.tb.crcTable())
+	payload = append(payload, byte(crc), byte(crc>>8), byte(crc>>16), byte(crc>>24))
+
+	switch w.framing {
+	case FramingSLIP:
+		w.frame = appendSLIP(w.frame[:0], payload)
+	default:
+		w.frame = appendCOBS(w.frame[:0], payload)
+		w.frame = append(w.frame, 0)
+	}
+
+	_, err = w.out.Write(w.frame)
+	return err
+}
+
+// ------------------------------------------------------------------------------
+
+// FrameReader reads frames written by FrameWriter and decodes them with the
+// TinyBin instance. A corrupted frame is reported once and reading resumes at
+// the next delimiter, so a single bad frame never derails the stream.
+type FrameReader struct {
+	// MaxSize is the largest encoded frame accepted, longer frames are
+	// discarded up to the next delimiter and reported as ErrFrame.
+	MaxSize int
+
+	tb      *TinyBin
+	in      io.ByteReader
+	framing Framing
+	raw     []byte // reused buffer holding the encoded frame
+}
+
+// NewFrameReader creates a frame reader decoding with this TinyBin instance.
+func (tb *TinyBin) NewFrameReader(r io.Reader, framing Framing) *FrameReader {
+	in, ok := r.(io.ByteReader)
+	if !ok {
+		in = bufio.NewReader(r)
+	}
+	return &FrameReader{MaxSize: defaultMaxFrameSize, tb: tb, in: in, framing: framing}
+}
+
+// ReadFrame reads the next complete frame and decodes it into v. It returns
+// ErrFrame or ErrChecksum for corrupted frames, io.EOF at the end of the stream
+// and io.ErrUnexpectedEOF if the stream ends in the middle of a frame.
+func (r *FrameReader) ReadFrame(v any) error {
+	raw, err := r.next()
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	switch r.framing {
+	case FramingSLIP:
+		payload, err = decodeSLIP(raw)
+	default:
+		payload, err = decodeCOBS(raw)
+	}
+	if err != nil || len(payload) < 4 {
+		return ErrFrame
+	}
+
+	body := payload[:len(payload)-4]
+	if crc32.Checksum(body, r.tb.crcTable()) != readUint32(payload[len(payload)-4:]) {
+		return ErrChecksum
+	}
+	return r.tb.Decode(body, v)
+}
+
+// next returns the bytes of the next non-empty frame without its delimiter.
+func (r *FrameReader) next() ([]byte, error) {
+	delimiter := byte(0)
+	if r.framing == FramingSLIP {
+		delimiter = slipEnd
+	}
+
+	r.raw = r.raw[:0]
+	oversized := false
+	for {
+		b, err := r.in.ReadByte()
+		if err != nil {
+			if err == io.EOF && (len(r.raw) > 0 || oversized) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		switch {
+		case b != delimiter:
+			if len(r.raw) >= r.MaxSize {
+				oversized = true
+				r.raw = r.raw[:0]
+			}
+			r.raw = append(r.raw, b)
+		case oversized:
+			return nil, ErrFrame
+		case len(r.raw) > 0:
+			return r.raw, nil
+		}
+		// Empty frames between consecutive delimiters are skipped
+	}
+}
+
+// ------------------------------------------------------------------------------
+
+// appendCOBS appends the COBS encoding of src to dst, without the delimiter.
+func appendCOBS(dst, src []byte) []byte {
+	codeIdx := len(dst)
+	dst = append(dst, 0)
+	code := byte(1)
+	for _, b := range src {
+		if b != 0 {
+			dst = append(dst, b)
+			code++
+		}
+		if b == 0 || code == 0xFF {
+			dst[codeIdx] = code
+			codeIdx = len(dst)
+			dst = append(dst, 0)
+			code = 1
+		}
+	}
+	dst[codeIdx] = code
+	return dst
+}
+
+// decodeCOBS decodes a COBS frame without its delimiter.
+func decodeCOBS(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		code := int(src[i])
+		if code == 0 || i+code > len(src) {
+			return nil, ErrFrame
+		}
+
+		out = append(out, src[i+1:i+code]...)
+		i += code
+		if code < 0xFF && i < len(src) {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// appendSLIP appends the SLIP encoding of src to dst, including the END
+// delimiters on both sides which flush any noise received before the frame.
+func appendSLIP(dst, src []byte) []byte {
+	dst = append(dst, slipEnd)
+	for _, b := range src {
+		switch b {
+		case slipEnd:
+			dst = append(dst, slipEsc, slipEscEnd)
+		case slipEsc:
+			dst = append(dst, slipEsc, slipEscEsc)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return append(dst, slipEnd)
+}
+
+// decodeSLIP decodes a SLIP frame without its delimiters.
+func decodeSLIP(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	for i := 0; i < len(src); i++ {
+		b := src[i]
+		if b == slipEsc {
+			if i++; i >= len(src) {
+				return nil, ErrFrame
+			}
+			switch src[i] {
+			case slipEscEnd:
+				b = slipEnd
+			case slipEscEsc:
+				b = slipEsc
+			default:
+				return nil, ErrFrame
+			}
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
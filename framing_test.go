@@ -0,0 +1,107 @@
+package tinybin
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCOBSRoundTrip(t *testing.T) {
+	long := bytes.Repeat([]byte{7}, 600)
+	cases := [][]byte{
+		{},
+		{0},
+		{0, 0},
+		{1, 2, 0, 3},
+		bytes.Repeat([]byte{1}, 254),
+		bytes.Repeat([]byte{1}, 255),
+		append(long, 0, 9),
+	}
+
+	for _, in := range cases {
+		enc := appendCOBS(nil, in)
+		if bytes.IndexByte(enc, 0) >= 0 {
+			t.Fatalf("COBS output for %d bytes contains a zero", len(in))
+		}
+		out, err := decodeCOBS(enc)
+		assertNoError(t, err)
+		assertEqualBytes(t, in, out)
+	}
+}
+
+func TestSLIPRoundTrip(t *testing.T) {
+	in := []byte{1, slipEnd, 2, slipEsc, 3}
+	enc := appendSLIP(nil, in)
+	assertEqualBytes(t, []byte{slipEnd, 1, slipEsc, slipEscEnd, 2, slipEsc, slipEscEsc, 3, slipEnd}, enc)
+
+	out, err := decodeSLIP(enc[1 : len(enc)-1])
+	assertNoError(t, err)
+	assertEqualBytes(t, in, out)
+}
+
+func TestFrameReaderResynchronizes(t *testing.T) {
+	for _, framing := range []Framing{FramingCOBS, FramingSLIP} {
+		tb := New()
+		var link bytes.Buffer
+		w := tb.NewFrameWriter(&link, framing)
+
+		assertNoError(t, w.WriteFrame(&basicStruct{Name: "first", Age: 1}))
+		start := link.Len()
+		assertNoError(t, w.WriteFrame(&basicStruct{Name: "second", Age: 2}))
+		assertNoError(t, w.WriteFrame(&basicStruct{Name: "third", Age: 3}))
+
+		// Flip a bit in the middle of the second frame
+		data := link.Bytes()
+		data[start+3] ^= 0x01
+
+		r := tb.NewFrameReader(bytes.NewReader(data), framing)
+		var v basicStruct
+		assertNoError(t, r.ReadFrame(&v))
+		assertEqual(t, "first", v.Name)
+
+		if err := r.ReadFrame(&v); err != ErrChecksum && err != ErrFrame {
+			t.Errorf("Expected corrupted frame error, got %v", err)
+		}
+
+		assertNoError(t, r.ReadFrame(&v))
+		assertEqual(t, "third", v.Name)
+
+		if err := r.ReadFrame(&v); err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+	}
+}
+
+func TestFrameReaderDroppedBytes(t *testing.T) {
+	tb := New()
+	var link bytes.Buffer
+	w := tb.NewFrameWriter(&link, FramingCOBS)
+	assertNoError(t, w.WriteFrame(&basicStruct{Name: "lost"}))
+	assertNoError(t, w.WriteFrame(&basicStruct{Name: "kept"}))
+
+	// Drop the first bytes, as if the receiver attached mid-frame
+	r := tb.NewFrameReader(bytes.NewReader(link.Bytes()[4:]), FramingCOBS)
+	var v basicStruct
+	if err := r.ReadFrame(&v); err == nil {
+		t.Error("Expected error for partial frame")
+	}
+	assertNoError(t, r.ReadFrame(&v))
+	assertEqual(t, "kept", v.Name)
+}
+
+func TestFrameReaderMaxSize(t *testing.T) {
+	tb := New()
+	var link bytes.Buffer
+	w := tb.NewFrameWriter(&link, FramingCOBS)
+	assertNoError(t, w.WriteFrame(&basicStruct{Name: string(bytes.Repeat([]byte("x"), 100))}))
+	assertNoError(t, w.WriteFrame(&basicStruct{Name: "small"}))
+
+	r := tb.NewFrameReader(&link, FramingCOBS)
+	r.MaxSize = 32
+	var v basicStruct
+	if err := r.ReadFrame(&v); err != ErrFrame {
+		t.Errorf("Expected ErrFrame, got %v", err)
+	}
+	assertNoError(t, r.ReadFrame(&v))
+	assertEqual(t, "small", v.Name)
+}
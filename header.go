@@ -0,0 +1,186 @@
+package tinybin
+
+import (
+	"bytes"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Header flags, written as a single byte in front of the payload when the
+// instance needs to describe how the payload was produced.
+const (
+	headerVersion    byte = 1 << iota // A uvarint message version follows the flags
+	headerCompressed                  // The uvarint length and the compressed payload follow
+	headerStrings                     // Strings are written through a per-message table
+
+	headerKnown = headerVersion | headerCompressed | headerStrings
+)
+
+// headerMarker starts the headers of instances with a LegacyVersion, telling
+// them apart from the payloads written without a header.
+var headerMarker = [2]byte{0xB1, 0x7E}
+
+// header describes the optional prefix of an encoded payload.
+type header struct {
+	flags   byte
+	version uint32
+}
+
+// usesHeader reports whether payloads of this instance carry a header.
+func (tb *TinyBin) usesHeader() bool {
+	return tb.compression.Compressor != nil || tb.stringDict || tb.versioned()
+}
+
+// encodeWithHeader writes the header followed by the payload, compressing the
+// payload when the instance is configured to.
+func (e *encoder) encodeWithHeader(c Codec, rv reflect.Value) error {
+	var h header
+	if e.tb.versioned() {
+		h.flags |= headerVersion
+		h.version = e.tb.versionOf(rv.Type())
+	}
+	if e.tb.stringDict {
+		h.flags |= headerStrings
+		e.dict = &stringDict{}
+		defer func() { e.dict = nil }()
+	}
+
+	if e.tb.compression.Compressor == nil {
+		e.writeHeader(h)
+		if err := c.EncodeTo(e, rv); err != nil {
+			return err
+		}
+		return e.err
+	}
+
+	// Compression needs the whole payload before the header can be written
+	var body bytes.Buffer
+	out := e.out
+	e.out = &body
+	err := c.EncodeTo(e, rv)
+	if err == nil {
+		err = e.err
+	}
+	e.out = out
+	if err != nil {
+		return err
+	}
+
+	payload, compressed, err := e.tb.compression.compress(body.Bytes())
+	if err != nil {
+		return err
+	}
+	if compressed {
+		h.flags |= headerCompressed
+	}
+
+	e.writeHeader(h)
+	if compressed {
+		e.WriteUvarint(uint64(len(payload)))
+	}
+	e.Write(payload)
+	return e.err
+}
+
+// writeHeader writes the header flags and the fields they announce.
+func (e *encoder) writeHeader(h header) {
+	if e.tb.legacyVersion != 0 {
+		e.Write(headerMarker[:])
+	}
+	e.scratch[0] = h.flags
+	e.Write(e.scratch[:1])
+	if h.flags&headerVersion != 0 {
+		e.WriteUvarint(uint64(h.version))
+	}
+}
+
+// readHeader reads a header written by writeHeader.
+func (d *decoder) readHeader() (h header, err error) {
+	if d.tb.legacyVersion != 0 {
+		var marked bool
+		if marked, err = d.marked(); err != nil || !marked {
+			// Payloads written before versioning are the bare value
+			return header{version: d.tb.legacyVersion}, err
+		}
+	}
+	if h.flags, err = d.reader.ReadByte(); err != nil {
+		return h, err
+	}
+	if h.flags&^headerKnown != 0 {
+		return h, Err(D.Binary, "header", D.Invalid, D.Format)
+	}
+
+	// Dictionary instances always set the flag, so a payload without it was
+	// written by an instance without StringDictionary
+	if d.tb.stringDict && h.flags&headerStrings == 0 {
+		return h, Err(D.Binary, "header", "string dictionary", D.Missing)
+	}
+	if h.flags&headerVersion != 0 {
+		var v uint64
+		if v, err = d.ReadUvarint(); err != nil {
+			return h, err
+		}
+		h.version = uint32(v)
+	}
+	return h, nil
+}
+
+// marked reports whether the input starts with the header marker, consuming it
+// if so.
+func (d *decoder) marked() (bool, error) {
+	var next []byte
+	switch r := d.reader.(type) {
+	case *sliceReader:
+		next = r.buffer[r.offset:]
+	case *streamReader:
+		p, ok := r.Reader.(interface{ Peek(int) ([]byte, error) })
+		if !ok {
+			return false, Err("LegacyVersion", D.Required, "Peek")
+		}
+		next, _ = p.Peek(len(headerMarker))
+	}
+	if len(next) < len(headerMarker) || next[0] != headerMarker[0] || next[1] != headerMarker[1] {
+		return false, nil
+	}
+	_, err := d.reader.Slice(len(headerMarker))
+	return true, err
+}
+
+// payload returns the decoder to read the payload from: the decoder itself, or
+// a decoder over the decompressed bytes for compressed payloads.
+func (d *decoder) payload(h header) (*decoder, error) {
+	if h.flags&headerStrings != 0 {
+		d.dict = &stringDict{}
+	}
+	if h.flags&headerCompressed == 0 {
+		return d, nil
+	}
+	if d.tb.compression.Compressor == nil {
+		return nil, Err(D.Binary, "compressor", D.Missing)
+	}
+
+	l, err := d.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > d.tb.messageLimit() {
+		return nil, ErrMessageSize
+	}
+	compressed, err := d.Slice(int(l))
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound the decompressed size too, as a small payload may inflate a lot
+	var raw []byte
+	if c, ok := d.tb.compression.Compressor.(limitedDecompressor); ok {
+		raw, err = c.decompress(compressed, d.tb.messageLimit())
+	} else if raw, err = d.tb.compression.Compressor.Decompress(compressed); err == nil && uint64(len(raw)) > d.tb.messageLimit() {
+		err = ErrMessageSize
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &decoder{reader: newSliceReader(raw), tb: d.tb, dict: d.dict}, nil
+}
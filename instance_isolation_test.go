@@ -9,9 +9,9 @@ import (
 // TestInstanceIsolation verifies that different TinyBin instances are completely isolated
 func TestInstanceIsolation(t *testing.T) {
 	type TestStruct struct {
-		ID   int    `binary:"id"`
-		Name string `binary:"name"`
-		Data []byte `binary:"data"`
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+		Data []byte `json:"data"`
 	}
 
 	// Create two separate instances
@@ -67,7 +67,7 @@ func TestConcurrentInstanceUsage(t *testing.T) {
 	instance2 := New()
 
 	type Counter struct {
-		Value int `binary:"value"`
+		Value int `json:"value"`
 	}
 
 	const numGoroutines = 10
@@ -165,9 +165,9 @@ func TestInstanceCacheIsolation(t *testing.T) {
 
 	// Use the same struct type with both instances
 	type CachedStruct struct {
-		A int    `binary:"a"`
-		B string `binary:"b"`
-		C []byte `binary:"c"`
+		A int    `json:"a"`
+		B string `json:"b"`
+		C []byte `json:"c"`
 	}
 
 	data := CachedStruct{A: 42, B: "test", C: []byte{1, 2, 3}}
@@ -224,7 +224,7 @@ func TestCustomLogging(t *testing.T) {
 	})
 
 	type SimpleStruct struct {
-		Value int `binary:"value"`
+		Value int `json:"value"`
 	}
 
 	data := SimpleStruct{Value: 123}
@@ -258,8 +258,8 @@ func TestMultipleProtocolSimulation(t *testing.T) {
 	kafkaHandler := New()
 
 	type Message struct {
-		ID      int    `binary:"id"`
-		Content string `binary:"content"`
+		ID      int    `json:"id"`
+		Content string `json:"content"`
 	}
 
 	// Each handler processes the same message independently
@@ -0,0 +1,241 @@
+package tinybin
+
+import (
+	"math/bits"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// BitPacking lets the integer slice codecs store each slice with
+// frame-of-reference bit packing when that is smaller than varints. Pass it to
+// New: tb := tinybin.New(tinybin.BitPacking{})
+//
+// A packed slice holds the minimum as a varint, the bit width of the largest
+// offset as a single byte and the offsets from the minimum packed at that
+// width, most significant bit first.
+//
+// With BitPacking enabled, the integer slice codecs write the slice length as
+// length<<2 | encoding, the encoding being 0 for plain varints, 1 for deltas
+// and 2 for packed offsets. AutoDelta alone keeps its length<<1 | encoding.
+type BitPacking struct{}
+
+// Integer slice encodings of instances with AutoDelta or BitPacking.
+const (
+	intSlicePlain  = 0
+	intSliceDelta  = 1
+	intSlicePacked = 2
+)
+
+var errIntSlice = Err("integer slice", D.Format, D.Invalid)
+
+// adaptiveInts reports whether integer slices choose their encoding per slice,
+// FixedWidth taking precedence.
+func (tb *TinyBin) adaptiveInts() bool {
+	return tb != nil && !tb.fixedWidth && (tb.autoDelta || tb.bitPacking)
+}
+
+// encodeIntSlice writes an integer slice with the smallest encoding enabled on
+// the instance.
+func encodeIntSlice(e *encoder, rv reflect.Value, signed bool) {
+	l := rv.Len()
+	var plain, deltas int
+	var prev, lo, hi uint64
+	for i := 0; i < l; i++ {
+		v := intBits(rv.Index(i), signed)
+		if signed {
+			plain += varintSize(int64(v))
+		} else {
+			plain += uvarintSize(v)
+		}
+		deltas += varintSize(int64(v - prev))
+		prev = v
+
+		if i == 0 || intLess(v, lo, signed) {
+			lo = v
+		}
+		if i == 0 || intLess(hi, v, signed) {
+			hi = v
+		}
+	}
+
+	mode, size := intSlicePlain, plain
+	if e.tb.autoDelta && deltas < size {
+		mode, size = intSliceDelta, deltas
+	}
+	width := uint(bits.Len64(hi - lo))
+	if e.tb.bitPacking && l > 0 && packedSize(lo, width, l, signed) < size {
+		mode = intSlicePacked
+	}
+
+	e.WriteUvarint(uint64(l)<<e.tb.modeBits() | uint64(mode))
+	switch mode {
+	case intSliceDelta:
+		writeDeltas(e, rv, signed)
+	case intSlicePacked:
+		writeMinimum(e, lo, signed)
+		e.scratch[0] = byte(width)
+		e.Write(e.scratch[:1])
+
+		w := bitWriter{buf: make([]byte, 0, (l*int(width)+7)/8)}
+		for i := 0; i < l; i++ {
+			w.writeBits(intBits(rv.Index(i), signed)-lo, width)
+		}
+		e.Write(w.buf)
+	default:
+		for i := 0; i < l; i++ {
+			if signed {
+				e.WriteVarint(rv.Index(i).Int())
+			} else {
+				e.WriteUvarint(rv.Index(i).Uint())
+			}
+		}
+	}
+}
+
+// decodeIntSlice reads an integer slice written by encodeIntSlice.
+func decodeIntSlice(d *decoder, rv reflect.Value, signed bool) error {
+	h, err := d.ReadUvarint()
+	bits := d.tb.modeBits()
+	if err != nil || h>>bits == 0 {
+		return err
+	}
+
+	l := int(h >> bits)
+	rv.Set(reflect.MakeSlice(rv.Type(), l, l))
+	return readIntBits(d, l, int(h&(1<<bits-1)), signed, func(i int, v uint64) {
+		setIntBits(rv.Index(i), v, signed)
+	})
+}
+
+// readIntBits reads n integers of the given encoding as uint64 bits.
+func readIntBits(d *decoder, n, mode int, signed bool, set func(i int, v uint64)) error {
+	switch mode {
+	case intSlicePlain, intSliceDelta:
+		var prev uint64
+		for i := 0; i < n; i++ {
+			var v uint64
+			if mode == intSliceDelta || signed {
+				x, err := d.ReadVarint()
+				if err != nil {
+					return err
+				}
+				v = uint64(x)
+			} else {
+				x, err := d.ReadUvarint()
+				if err != nil {
+					return err
+				}
+				v = x
+			}
+
+			if mode == intSliceDelta {
+				v += prev
+				prev = v
+			}
+			set(i, v)
+		}
+		return nil
+
+	case intSlicePacked:
+		lo, err := readMinimum(d, signed)
+		if err != nil {
+			return err
+		}
+		width, err := d.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if width > 64 {
+			return errIntSlice
+		}
+		packed, err := d.Slice((n*int(width) + 7) / 8)
+		if err != nil {
+			return err
+		}
+
+		r := bitReader{buf: packed}
+		for i := 0; i < n; i++ {
+			off, err := r.readBits(uint(width))
+			if err != nil {
+				return err
+			}
+			set(i, lo+off)
+		}
+		return nil
+	}
+	return errIntSlice
+}
+
+// decodeIntItems decodes the elements of an integer slice without a Go type,
+// for delta tagged fields and integer slices of adaptive instances.
+func decodeIntItems(d *decoder, s *Schema, v *Value) error {
+	h, err := d.ReadUvarint()
+	if err != nil {
+		return err
+	}
+
+	l, mode := h, intSliceDelta
+	if s.wire() != wireDelta {
+		bits := d.tb.modeBits()
+		l, mode = h>>bits, int(h&(1<<bits-1))
+	}
+	signed := s.Elem.wire() == wireVarint
+
+	v.Items = make([]Value, 0, min(l, 1024))
+	return readIntBits(d, int(l), mode, signed, func(_ int, bits uint64) {
+		item := Value{Name: s.Elem.Name, Kind: s.Elem.Kind, Scalar: bits}
+		if signed {
+			item.Scalar = int64(bits)
+		}
+		v.Items = append(v.Items, item)
+	})
+}
+
+// modeBits returns the number of low bits of the slice length holding the
+// encoding: two with BitPacking, one with AutoDelta alone.
+func (tb *TinyBin) modeBits() uint {
+	if tb.bitPacking {
+		return 2
+	}
+	return 1
+}
+
+// adaptiveInts reports whether the decoder reads integer slices written by an
+// instance with AutoDelta or BitPacking.
+func (d *decoder) adaptiveInts() bool {
+	return d.tb.adaptiveInts()
+}
+
+// intLess compares integer bits as signed or unsigned values.
+func intLess(a, b uint64, signed bool) bool {
+	if signed {
+		return int64(a) < int64(b)
+	}
+	return a < b
+}
+
+// packedSize returns the number of bytes of a bit packed slice.
+func packedSize(lo uint64, width uint, n int, signed bool) int {
+	size := uvarintSize(lo)
+	if signed {
+		size = varintSize(int64(lo))
+	}
+	return size + 1 + (n*int(width)+7)/8
+}
+
+func writeMinimum(e *encoder, lo uint64, signed bool) {
+	if signed {
+		e.WriteVarint(int64(lo))
+	} else {
+		e.WriteUvarint(lo)
+	}
+}
+
+func readMinimum(d *decoder, signed bool) (uint64, error) {
+	if signed {
+		v, err := d.ReadVarint()
+		return uint64(v), err
+	}
+	return d.ReadUvarint()
+}
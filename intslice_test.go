@@ -0,0 +1,127 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type adcBatch struct {
+	Counts  []uint16
+	Offsets []int32
+	IDs     []uint64
+}
+
+func newADCBatch(n int) adcBatch {
+	var b adcBatch
+	for i := 0; i < n; i++ {
+		b.Counts = append(b.Counts, uint16(2000+(i*7919)%2000))
+		b.Offsets = append(b.Offsets, int32(-500+(i*31)%900))
+		b.IDs = append(b.IDs, uint64(i*i))
+	}
+	return b
+}
+
+func TestBitPackingRoundTrip(t *testing.T) {
+	tb := New(BitPacking{})
+	in := newADCBatch(500)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out adcBatch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	plain, err := New().Encode(&in)
+	assertNoError(t, err)
+	if len(data) >= len(plain)*3/4 {
+		t.Errorf("Expected packed payload (%d bytes) to be well below plain (%d bytes)", len(data), len(plain))
+	}
+}
+
+func TestBitPackingLayout(t *testing.T) {
+	tb := New(BitPacking{})
+
+	// 4 values in [1000, 1003]: min 1000, width 2, one byte of offsets
+	counts := []uint16{1000, 1003, 1001, 1002}
+	data, err := tb.Encode(&counts)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{4<<2 | intSlicePacked, 0xE8, 0x07, 2, 0b00_11_01_10}, data)
+
+	// Identical values pack to zero bits
+	same := make([]int64, 100)
+	for i := range same {
+		same[i] = -123456
+	}
+	data, err = tb.Encode(&same)
+	assertNoError(t, err)
+	assertEqualInt(t, 2+3+1, len(data))
+
+	var out []int64
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, same, out)
+
+	// Small values stay plain varints
+	small := []uint32{1, 2, 3}
+	data, err = tb.Encode(&small)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{3 << 2, 1, 2, 3}, data)
+}
+
+func TestBitPackingExtremes(t *testing.T) {
+	tb := New(BitPacking{}, AutoDelta{})
+	for _, in := range []any{
+		&[]int64{math.MinInt64, math.MaxInt64, 0, -1},
+		&[]uint64{0, math.MaxUint64, 1 << 63},
+		&[]int8{-128, 127, -128, 127, 0, 0, 0, 0, 0, 0},
+	} {
+		data, err := tb.Encode(in)
+		assertNoError(t, err)
+
+		out := reflect.New(reflect.TypeOf(in).Elem()).Interface()
+		assertNoError(t, tb.Decode(data, out))
+		assertEqual(t, in, out)
+	}
+}
+
+func TestBitPackingInvalid(t *testing.T) {
+	tb := New(BitPacking{})
+	var out []uint16
+	for _, data := range [][]byte{
+		{2<<2 | intSlicePacked, 0, 65},   // width above 64
+		{2<<2 | intSlicePacked, 0, 8, 1}, // missing packed bytes
+		{2<<2 | 3, 1, 2},                 // unknown encoding
+	} {
+		if err := tb.Decode(data, &out); err == nil {
+			t.Errorf("Expected error decoding %v", data)
+		}
+	}
+}
+
+func TestBitPackingSchema(t *testing.T) {
+	tb := New(BitPacking{})
+	schema, err := tb.Schema(adcBatch{})
+	assertNoError(t, err)
+
+	in := newADCBatch(50)
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var ids struct{ IDs []uint64 }
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &ids))
+	assertEqual(t, in.IDs, ids.IDs)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, adcBatch{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	offsets, _ := v.Field("Offsets")
+	assertEqual(t, int64(in.Offsets[42]), offsets.Items[42].Scalar)
+}
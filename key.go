@@ -0,0 +1,291 @@
+package tinybin
+
+import (
+	"io"
+	"math"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// KeyEncoder encodes values into keys whose bytewise order, as compared by
+// bytes.Compare, matches the Go ordering of the values. The keys suit ordered
+// key-value stores and are unrelated to the regular tinybin format.
+//
+// Integers are written big-endian in the width of their kind, with the sign
+// bit flipped for signed kinds. Floats are written big-endian with the sign bit
+// flipped for positive values and every bit flipped for negative ones. Strings
+// and byte slices escape 0x00 as 0x00 0xFF and end with 0x00 0x01. Structs and
+// arrays are the concatenation of their elements, so a struct is a tuple
+// ordered field by field. Pointers are prefixed by 0 when nil and 1 otherwise.
+type KeyEncoder struct{}
+
+// Encode returns the key of a value.
+func (k KeyEncoder) Encode(v any) ([]byte, error) {
+	return k.Append(nil, v)
+}
+
+// Append appends the key of a value to dst.
+func (KeyEncoder) Append(dst []byte, v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return dst, Errf("cannot encode nil value")
+	}
+	return appendKey(dst, rv)
+}
+
+// Decode decodes a key produced by Encode into target, which must be a pointer
+// to a value of the encoded type.
+func (KeyEncoder) Decode(key []byte, target any) error {
+	rv := reflect.Indirect(reflect.ValueOf(target))
+	if !rv.CanAddr() {
+		return Err(D.Binary, "decoder", D.Required, D.Type, D.Pointer)
+	}
+
+	rest, err := decodeKey(key, rv)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return Err("key", D.Format, D.Invalid)
+	}
+	return nil
+}
+
+// keyWidth returns the width in bytes of the integer and float kinds.
+func keyWidth(k reflect.Kind) int {
+	switch Kind(k) {
+	case K.Int8, K.Uint8:
+		return 1
+	case K.Int16, K.Uint16:
+		return 2
+	case K.Int32, K.Uint32, K.Float32:
+		return 4
+	}
+	return 8
+}
+
+func appendKey(dst []byte, rv reflect.Value) ([]byte, error) {
+	switch Kind(rv.Kind()) {
+	case K.Bool:
+		if rv.Bool() {
+			return append(dst, 1), nil
+		}
+		return append(dst, 0), nil
+
+	case K.Int, K.Int8, K.Int16, K.Int32, K.Int64:
+		w := keyWidth(rv.Kind())
+		return appendBigEndian(dst, uint64(rv.Int())^1<<(8*w-1), w), nil
+
+	case K.Uint, K.Uint8, K.Uint16, K.Uint32, K.Uint64:
+		return appendBigEndian(dst, rv.Uint(), keyWidth(rv.Kind())), nil
+
+	case K.Float32:
+		bits := uint64(math.Float32bits(float32(rv.Float())))
+		if bits&(1<<31) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 31
+		}
+		return appendBigEndian(dst, bits, 4), nil
+
+	case K.Float64:
+		bits := math.Float64bits(rv.Float())
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return appendBigEndian(dst, bits, 8), nil
+
+	case K.String:
+		return appendKeyBytes(dst, rv.String()), nil
+
+	case K.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		return appendKeyBytes(dst, string(rv.Bytes())), nil
+
+	case K.Array:
+		var err error
+		for i := 0; i < rv.Len() && err == nil; i++ {
+			dst, err = appendKey(dst, rv.Index(i))
+		}
+		return dst, err
+
+	case K.Struct:
+		var err error
+		for _, i := range scanStruct(rv.Type()).fields {
+			if dst, err = appendKey(dst, rv.Field(i)); err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+
+	case K.Pointer:
+		if rv.IsNil() {
+			return append(dst, 0), nil
+		}
+		return appendKey(append(dst, 1), rv.Elem())
+	}
+	return dst, Err("key", D.Type, rv.Type().String(), D.Not, D.Supported)
+}
+
+// appendKeyBytes appends an escaped, terminated string so that a string sorts
+// before every string it is a prefix of.
+func appendKeyBytes(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x00 {
+			dst = append(dst, 0x00, 0xFF)
+		} else {
+			dst = append(dst, s[i])
+		}
+	}
+	return append(dst, 0x00, 0x01)
+}
+
+func appendBigEndian(dst []byte, v uint64, width int) []byte {
+	for shift := 8 * (width - 1); shift >= 0; shift -= 8 {
+		dst = append(dst, byte(v>>shift))
+	}
+	return dst
+}
+
+// ------------------------------------------------------------------------------
+
+// decodeKey decodes the key of rv and returns the remaining bytes.
+func decodeKey(key []byte, rv reflect.Value) ([]byte, error) {
+	switch Kind(rv.Kind()) {
+	case K.Bool:
+		if len(key) < 1 || key[0] > 1 {
+			return key, io.ErrUnexpectedEOF
+		}
+		rv.SetBool(key[0] == 1)
+		return key[1:], nil
+
+	case K.Int, K.Int8, K.Int16, K.Int32, K.Int64:
+		w := keyWidth(rv.Kind())
+		v, rest, err := readBigEndian(key, w)
+		if err == nil {
+			v ^= 1 << (8*w - 1)
+			// Sign extend from the width of the kind
+			rv.SetInt(int64(v<<(64-8*w)) >> (64 - 8*w))
+		}
+		return rest, err
+
+	case K.Uint, K.Uint8, K.Uint16, K.Uint32, K.Uint64:
+		v, rest, err := readBigEndian(key, keyWidth(rv.Kind()))
+		if err == nil {
+			rv.SetUint(v)
+		}
+		return rest, err
+
+	case K.Float32:
+		bits, rest, err := readBigEndian(key, 4)
+		if err == nil {
+			if bits&(1<<31) != 0 {
+				bits &^= 1 << 31
+			} else {
+				bits = ^bits & math.MaxUint32
+			}
+			rv.SetFloat(float64(math.Float32frombits(uint32(bits))))
+		}
+		return rest, err
+
+	case K.Float64:
+		bits, rest, err := readBigEndian(key, 8)
+		if err == nil {
+			if bits&(1<<63) != 0 {
+				bits &^= 1 << 63
+			} else {
+				bits = ^bits
+			}
+			rv.SetFloat(math.Float64frombits(bits))
+		}
+		return rest, err
+
+	case K.String:
+		b, rest, err := readKeyBytes(key)
+		if err == nil {
+			rv.SetString(string(b))
+		}
+		return rest, err
+
+	case K.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		b, rest, err := readKeyBytes(key)
+		if err == nil {
+			rv.SetBytes(b)
+		}
+		return rest, err
+
+	case K.Array:
+		var err error
+		for i := 0; i < rv.Len() && err == nil; i++ {
+			key, err = decodeKey(key, rv.Index(i))
+		}
+		return key, err
+
+	case K.Struct:
+		var err error
+		for _, i := range scanStruct(rv.Type()).fields {
+			if key, err = decodeKey(key, rv.Field(i)); err != nil {
+				return key, err
+			}
+		}
+		return key, nil
+
+	case K.Pointer:
+		if len(key) < 1 || key[0] > 1 {
+			return key, io.ErrUnexpectedEOF
+		}
+		if key[0] == 0 {
+			rv.Set(reflect.Zero(rv.Type()))
+			return key[1:], nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeKey(key[1:], rv.Elem())
+	}
+	return key, Err("key", D.Type, rv.Type().String(), D.Not, D.Supported)
+}
+
+// readKeyBytes reads an escaped, terminated string written by appendKeyBytes.
+func readKeyBytes(key []byte) ([]byte, []byte, error) {
+	var out []byte
+	for i := 0; i+1 < len(key); i++ {
+		if key[i] != 0x00 {
+			out = append(out, key[i])
+			continue
+		}
+
+		switch key[i+1] {
+		case 0x01:
+			if out == nil {
+				out = []byte{}
+			}
+			return out, key[i+2:], nil
+		case 0xFF:
+			out = append(out, 0x00)
+			i++
+		default:
+			return nil, key, Err("key", D.Format, D.Invalid)
+		}
+	}
+	return nil, key, io.ErrUnexpectedEOF
+}
+
+func readBigEndian(key []byte, width int) (uint64, []byte, error) {
+	if len(key) < width {
+		return 0, key, io.ErrUnexpectedEOF
+	}
+	var v uint64
+	for _, b := range key[:width] {
+		v = v<<8 | uint64(b)
+	}
+	return v, key[width:], nil
+}
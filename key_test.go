@@ -0,0 +1,119 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"testing"
+)
+
+type keyStruct struct {
+	Tenant string
+	Day    int32
+	Score  float64
+	Seq    uint16
+	Skip   string `binary:"-"`
+}
+
+func TestKeyOrderScalars(t *testing.T) {
+	var keys KeyEncoder
+	ints := []int64{math.MinInt64, -1 << 40, -300, -1, 0, 1, 127, 128, 1 << 40, math.MaxInt64}
+	floats := []float64{math.Inf(-1), -1e300, -2.5, -math.SmallestNonzeroFloat64, 0, math.SmallestNonzeroFloat64, 1, 1e300, math.Inf(1)}
+	floats32 := []float32{float32(math.Inf(-1)), -math.MaxFloat32, -2.5, -math.SmallestNonzeroFloat32, 0, math.SmallestNonzeroFloat32, 1, math.MaxFloat32, float32(math.Inf(1))}
+	strs := []string{"", "\x00", "\x00\x00", "\x00a", "a", "a\x00", "a\x00b", "ab", "b"}
+
+	assertSorted := func(name string, n int, key func(i int) []byte) {
+		t.Helper()
+		for i := 1; i < n; i++ {
+			if bytes.Compare(key(i-1), key(i)) >= 0 {
+				t.Errorf("%s: key %d does not sort before key %d", name, i-1, i)
+			}
+		}
+	}
+	encode := func(v any) []byte {
+		key, err := keys.Encode(v)
+		assertNoError(t, err)
+		return key
+	}
+
+	assertSorted("int64", len(ints), func(i int) []byte { return encode(ints[i]) })
+	assertSorted("int8", 3, func(i int) []byte { return encode([]int8{-128, 0, 127}[i]) })
+	assertSorted("uint", 3, func(i int) []byte { return encode([]uint{0, 255, math.MaxUint}[i]) })
+	assertSorted("float64", len(floats), func(i int) []byte { return encode(floats[i]) })
+	assertSorted("float32", len(floats32), func(i int) []byte { return encode(floats32[i]) })
+	assertSorted("string", len(strs), func(i int) []byte { return encode(strs[i]) })
+}
+
+func TestKeyOrderStruct(t *testing.T) {
+	var keys KeyEncoder
+	values := []keyStruct{
+		{Tenant: "a", Day: -5, Score: 3, Seq: 9},
+		{Tenant: "a", Day: 1, Score: -1, Seq: 1},
+		{Tenant: "a", Day: 1, Score: 2, Seq: 0},
+		{Tenant: "a", Day: 1, Score: 2, Seq: 1},
+		{Tenant: "ab", Day: -100},
+		{Tenant: "b"},
+	}
+
+	encoded := make([][]byte, len(values))
+	for i := range values {
+		var err error
+		encoded[i], err = keys.Encode(&values[i])
+		assertNoError(t, err)
+	}
+
+	shuffled := [][]byte{encoded[3], encoded[5], encoded[0], encoded[4], encoded[2], encoded[1]}
+	sort.Slice(shuffled, func(i, j int) bool { return bytes.Compare(shuffled[i], shuffled[j]) < 0 })
+	for i := range shuffled {
+		var out keyStruct
+		assertNoError(t, keys.Decode(shuffled[i], &out))
+		assertEqual(t, values[i], out)
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	type tuple struct {
+		B     bool
+		I8    int8
+		I     int
+		U32   uint32
+		F32   float32
+		Data  []byte
+		Name  string
+		Ptr   *int16
+		Array [2]uint8
+	}
+
+	var keys KeyEncoder
+	n := int16(-7)
+	in := tuple{B: true, I8: -3, I: -1 << 50, U32: 42, F32: -1.5, Data: []byte{0, 1, 0}, Name: "x\x00y", Ptr: &n, Array: [2]uint8{1, 2}}
+
+	key, err := keys.Encode(&in)
+	assertNoError(t, err)
+	var out tuple
+	assertNoError(t, keys.Decode(key, &out))
+	assertEqual(t, in, out)
+
+	key, err = keys.Encode(&tuple{})
+	assertNoError(t, err)
+	out = tuple{Ptr: &n}
+	assertNoError(t, keys.Decode(key, &out))
+	assertEqual(t, tuple{Data: []byte{}}, out)
+}
+
+func TestKeyErrors(t *testing.T) {
+	var keys KeyEncoder
+	if _, err := keys.Encode([]int{1}); err == nil {
+		t.Error("Expected error for unsupported slice")
+	}
+
+	key, err := keys.Encode("abc")
+	assertNoError(t, err)
+	var s string
+	if err := keys.Decode(key[:len(key)-1], &s); err == nil {
+		t.Error("Expected error for truncated key")
+	}
+	if err := keys.Decode(append(key, 0), &s); err == nil {
+		t.Error("Expected error for trailing bytes")
+	}
+}
@@ -0,0 +1,239 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// CLayout makes the instance write values the way C compilers lay out structs,
+// so one struct definition serves tinybin peers and C firmware alike. Pass it
+// to New: tb := tinybin.New(tinybin.CLayout{BigEndian: true})
+//
+// Every integer takes the natural size of its kind as with FixedWidth, int and
+// uint being 8 bytes, bools take one byte, floats their IEEE 754 size, and
+// arrays and nested structs are written inline without any length prefix.
+// Strings, slices and pointers keep their tinybin encoding since C structs have
+// no equivalent, and Align leaves structs holding them unpadded, their layout
+// not being fixed.
+type CLayout struct {
+	BigEndian bool // Multi-byte values are written most significant byte first
+	Align     bool // Fields are padded to their natural alignment, as in non packed C structs
+}
+
+// byteOrder selects the order of the bytes of fixed size values, the default
+// being the order of the instance.
+type byteOrder int8
+
+const (
+	orderDefault byteOrder = iota
+	orderLittle
+	orderBig
+)
+
+// byteOrderCodec writes a field tagged with `binary:"be"` or `binary:"le"` in
+// that byte order, whatever the order of the instance.
+type byteOrderCodec struct {
+	elemCodec  Codec
+	order      byteOrder
+	needsFixed bool // The field holds varints unless the instance makes integers fixed width
+}
+
+var errByteOrder = Err("be", "le", D.Required, "fixed")
+
+// Encode encodes a value into the encoder.
+func (c *byteOrderCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	if c.needsFixed && !e.tb.fixedInts() {
+		return errByteOrder
+	}
+	prev := e.order
+	e.order = c.order
+	err := c.elemCodec.EncodeTo(e, rv)
+	e.order = prev
+	return err
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *byteOrderCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if c.needsFixed && !d.fixedInts() {
+		return errByteOrder
+	}
+	prev := d.order
+	d.order = c.order
+	err := c.elemCodec.DecodeTo(d, rv)
+	d.order = prev
+	return err
+}
+
+// tagByteOrder returns the byte order selected by the tag options.
+func tagByteOrder(opts tagOptions) byteOrder {
+	switch {
+	case opts.Has("be"):
+		return orderBig
+	case opts.Has("le"):
+		return orderLittle
+	}
+	return orderDefault
+}
+
+// newByteOrderCodec returns the codec writing a field of type t in the byte
+// order of its tag, which must select values of a fixed size: floats and
+// integers, fixed by their tag or by the instance, or arrays, slices and
+// pointers of them.
+func newByteOrderCodec(t reflect.Type, elemCodec Codec, order byteOrder, fixed bool) (Codec, error) {
+	ordered, needsFixed := orderedLayout(t, fixed)
+	if !ordered {
+		return nil, Err("be", "le", D.Type, t.String(), D.Not, D.Supported)
+	}
+	return &byteOrderCodec{elemCodec: elemCodec, order: order, needsFixed: needsFixed}, nil
+}
+
+// orderedLayout reports whether values of type t are made of values with a byte
+// order, and whether that needs an instance writing integers fixed width. fixed
+// reports whether the tag of the field already fixes their width.
+func orderedLayout(t reflect.Type, fixed bool) (ordered, needsFixed bool) {
+	switch k := Kind(t.Kind()); {
+	case k == K.Float32 || k == K.Float64:
+		return true, false
+	case signedKind(k) || unsignedKind(k):
+		return true, !fixed
+	case k == K.Array || k == K.Pointer || k == K.Slice && t.Elem().Kind() != reflect.Uint8:
+		return orderedLayout(t.Elem(), fixed)
+	}
+	return false, false
+}
+
+// bigEndian reports whether fixed size values are written most significant byte
+// first.
+func (e *encoder) bigEndian() bool {
+	if e.order != orderDefault {
+		return e.order == orderBig
+	}
+	return e.tb != nil && e.tb.bigEndian
+}
+
+// bigEndian reports whether fixed size values are read most significant byte
+// first.
+func (d *decoder) bigEndian() bool {
+	if d.order != orderDefault {
+		return d.order == orderBig
+	}
+	return d.tb != nil && d.tb.bigEndian
+}
+
+// aligned reports whether struct fields are padded to their natural alignment.
+func (tb *TinyBin) aligned() bool {
+	return tb != nil && tb.align
+}
+
+// ------------------------------------------------------------------------------
+
+// padCodec writes the zero bytes aligning the next field of a struct, or nothing
+// on instances without CLayout alignment. It ignores the value it is given.
+type padCodec int
+
+// Encode encodes a value into the encoder.
+func (c padCodec) EncodeTo(e *encoder, _ reflect.Value) error {
+	if e.tb.aligned() {
+		clear(e.scratch[:c])
+		e.Write(e.scratch[:c])
+	}
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c padCodec) DecodeTo(d *decoder, _ reflect.Value) (err error) {
+	if d.tb.aligned() {
+		_, err = d.Slice(int(c))
+	}
+	return err
+}
+
+// cLayout returns the size and the alignment of the described type in the C
+// layout, ok being false for types without a fixed size.
+func (s *Schema) cLayout() (size, align int, ok bool) {
+	if _, ok := s.condition(); ok {
+		return 0, 0, false
+	}
+	if w := s.fixedWidth(); w > 0 {
+		return w, w, true
+	}
+
+	switch s.wire() {
+	case wireBool:
+		return 1, 1, true
+	case wireVarint, wireUvarint:
+		n := fixedSize(s.Kind)
+		return n, n, true
+	case wireFloat32:
+		return 4, 4, true
+	case wireFloat64:
+		return 8, 8, true
+	case wireSized:
+		return s.byteSize(), 1, true
+	case wireArray:
+		size, align, ok = s.Elem.cLayout()
+		return size * s.Len, align, ok
+	case wireStruct:
+		_, size, align, ok = s.structLayout()
+		return size, align, ok
+	}
+	return 0, 0, false
+}
+
+// structLayout returns the padding before each field of the struct, and after
+// the last one at index len(s.Fields), along with its size and alignment. Runs
+// of bitfields are byte aligned. ok is false when a field has no fixed size.
+func (s *Schema) structLayout() (pads []int, size, align int, ok bool) {
+	pads = make([]int, len(s.Fields)+1)
+	align = 1
+	for i := 0; i < len(s.Fields); i = bitRun(s.Fields, i) {
+		fieldSize, fieldAlign := 0, 1
+		if s.Fields[i].bitWidth() > 0 {
+			fieldSize = schemaBitGroup(s.Fields[i:bitRun(s.Fields, i)]).size()
+		} else if fieldSize, fieldAlign, ok = s.Fields[i].cLayout(); !ok {
+			return nil, 0, 0, false
+		}
+
+		pads[i] = (fieldAlign - size%fieldAlign) % fieldAlign
+		size += pads[i] + fieldSize
+		align = max(align, fieldAlign)
+	}
+	pads[len(s.Fields)] = (align - size%align) % align
+	return pads, size + pads[len(s.Fields)], align, true
+}
+
+// structPads returns the padding of the struct read by the decoder, or nil when
+// there is none.
+func (d *decoder) structPads(s *Schema) []int {
+	if !d.tb.aligned() {
+		return nil
+	}
+	pads, _, _, _ := s.structLayout()
+	return pads
+}
+
+// skipPad skips the padding before field i.
+func (d *decoder) skipPad(pads []int, i int) (err error) {
+	if pads != nil && pads[i] > 0 {
+		_, err = d.Slice(pads[i])
+	}
+	return err
+}
+
+// byteOrder returns the byte order selected by the tag of the described field.
+func (s *Schema) byteOrder() byteOrder {
+	return tagByteOrder(tagOptions(s.Tag))
+}
+
+// resolveByteOrder resolves a field tagged with a byte order as the untagged
+// field read in that order.
+func (tb *TinyBin) resolveByteOrder(w *Schema, t reflect.Type) (Codec, error) {
+	plain := *w
+	plain.Tag = tagOptions(w.Tag).without("be", "le")
+	codec, err := tb.resolve(&plain, t)
+	if err != nil {
+		return nil, err
+	}
+	return &byteOrderCodec{elemCodec: codec, order: w.byteOrder()}, nil
+}
@@ -0,0 +1,234 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+// telemetry mirrors struct telemetry { uint8_t id; int16_t temp; uint32_t ticks;
+// float volts; uint8_t flags[3]; } of the firmware.
+type telemetry struct {
+	ID    uint8
+	Temp  int16
+	Ticks uint32
+	Volts float32
+	Flags [3]uint8
+}
+
+var telemetryFixture = telemetry{ID: 1, Temp: -2, Ticks: 0x01020304, Volts: 1.5, Flags: [3]uint8{7, 8, 9}}
+
+func TestCLayoutPacked(t *testing.T) {
+	tb := New(CLayout{})
+	data, err := tb.Encode(&telemetryFixture)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		0x01,       // ID
+		0xFE, 0xFF, // Temp
+		0x04, 0x03, 0x02, 0x01, // Ticks
+		0x00, 0x00, 0xC0, 0x3F, // Volts
+		7, 8, 9, // Flags
+	}, data)
+
+	var out telemetry
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, telemetryFixture, out)
+}
+
+func TestCLayoutBigEndian(t *testing.T) {
+	tb := New(CLayout{BigEndian: true})
+	data, err := tb.Encode(&telemetryFixture)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		0x01,
+		0xFF, 0xFE,
+		0x01, 0x02, 0x03, 0x04,
+		0x3F, 0xC0, 0x00, 0x00,
+		7, 8, 9,
+	}, data)
+
+	var out telemetry
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, telemetryFixture, out)
+
+	// Fields may override the order of the instance
+	type mixed struct {
+		A uint16    `binary:"le"`
+		B [2]uint16 `binary:"be"`
+		C uint16
+	}
+	in := mixed{A: 0x0102, B: [2]uint16{0x0304, 0x0506}, C: 0x0708}
+	data, err = tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0x02, 0x01, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, data)
+
+	var mixedOut mixed
+	assertNoError(t, tb.Decode(data, &mixedOut))
+	assertEqual(t, in, mixedOut)
+}
+
+func TestCLayoutAligned(t *testing.T) {
+	tb := New(CLayout{Align: true})
+	data, err := tb.Encode(&telemetryFixture)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{
+		0x01, 0x00, // ID, padding
+		0xFE, 0xFF,
+		0x04, 0x03, 0x02, 0x01,
+		0x00, 0x00, 0xC0, 0x3F,
+		7, 8, 9, 0x00, // Flags, padding
+	}, data)
+	assertEqualInt(t, int(unsafe.Sizeof(telemetry{})), len(data))
+
+	var out telemetry
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, telemetryFixture, out)
+
+	type frame struct {
+		Kind    uint8
+		Samples [2]telemetry
+		Flag    bool
+		Time    int64
+		Battery uint8 `binary:"bits=4"`
+		Mode    uint8 `binary:"bits=4"`
+	}
+	in := frame{Kind: 3, Samples: [2]telemetry{telemetryFixture, {ID: 2}}, Flag: true, Time: -1, Battery: 9, Mode: 2}
+	data, err = tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualInt(t, 1+3+16*2+1+3+8+1+7, len(data))
+
+	var frameOut frame
+	assertNoError(t, tb.Decode(data, &frameOut))
+	assertEqual(t, in, frameOut)
+
+	// Types without a fixed size are not padded
+	type named struct {
+		ID   uint8
+		Name string
+		Seq  uint32
+	}
+	data, err = tb.Encode(&named{ID: 1, Name: "a", Seq: 2})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 1, 'a', 2, 0, 0, 0}, data)
+}
+
+func TestByteOrderTag(t *testing.T) {
+	type header struct {
+		Magic uint32  `binary:"fixed,be"`
+		Scale float64 `binary:"be"`
+		Count uint32
+	}
+
+	tb := New()
+	in := header{Magic: 0xCAFEBABE, Scale: math.Pi, Count: 300}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0xCA, 0xFE, 0xBA, 0xBE}, data[:4])
+	assertEqualBytes(t, []byte{0x40, 0x09, 0x21, 0xFB, 0x54, 0x44, 0x2D, 0x18}, data[4:12])
+	assertEqualBytes(t, []byte{0xAC, 0x02}, data[12:])
+
+	var out header
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	// Readers may drop the tag
+	schema, err := tb.Schema(header{})
+	assertNoError(t, err)
+	var plain struct {
+		Magic uint64
+		Scale float64
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &plain))
+	assertEqual(t, uint64(0xCAFEBABE), plain.Magic)
+	assertEqual(t, math.Pi, plain.Scale)
+
+	cur, err := tb.Schema(struct {
+		Scale float64 `binary:"le"`
+	}{})
+	assertNoError(t, err)
+	old, err := tb.Schema(struct {
+		Scale float64 `binary:"be"`
+	}{})
+	assertNoError(t, err)
+	issues := CheckCompatibility(old, cur)
+	assertEqualInt(t, 1, len(issues))
+	assertEqual(t, "byte order changed", issues[0].Reason)
+}
+
+func TestCLayoutSchema(t *testing.T) {
+	tb := New(CLayout{BigEndian: true, Align: true})
+	type reading struct {
+		Flag  bool
+		Level int32
+		Temp  int16 `binary:"le"`
+	}
+	schema, err := tb.Schema(reading{})
+	assertNoError(t, err)
+
+	in := reading{Flag: true, Level: -5, Temp: 300}
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{1, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFB, 0x2C, 0x01, 0, 0}, data)
+
+	var subset struct {
+		Temp  int64
+		Level int64
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &subset))
+	assertEqual(t, int64(300), subset.Temp)
+	assertEqual(t, int64(-5), subset.Level)
+
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, reading{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	for i := 0; i < 2; i++ {
+		v, err := r.NextValue()
+		assertNoError(t, err)
+		temp, _ := v.Field("Temp")
+		assertEqual(t, int64(300), temp.Scalar)
+		level, _ := v.Field("Level")
+		assertEqual(t, int64(-5), level.Scalar)
+	}
+}
+
+func TestByteOrderTagInvalid(t *testing.T) {
+	tb := New()
+	for _, in := range []any{
+		&struct {
+			Name string `binary:"be"`
+		}{},
+		&struct {
+			On bool `binary:"le"`
+		}{},
+		&struct {
+			Data []byte `binary:"be"`
+		}{},
+		// Varints have no byte order
+		&struct {
+			Count uint32 `binary:"be"`
+		}{},
+		&struct {
+			Counts []int16 `binary:"le"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for byte order tag in %T", in)
+		}
+	}
+
+	// Integers are fixed width on FixedWidth and CLayout instances
+	in := struct {
+		Count uint16 `binary:"be"`
+	}{Count: 0x0102}
+	for _, tb := range []*TinyBin{New(FixedWidth{}), New(CLayout{})} {
+		data, err := tb.Encode(&in)
+		assertNoError(t, err)
+		assertEqualBytes(t, []byte{0x01, 0x02}, data)
+	}
+}
@@ -0,0 +1,185 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// versionEntry associates a Go type with the message version it represents.
+type versionEntry struct {
+	Version uint32
+	Type    reflect.Type
+}
+
+// migrationEntry converts a value of one version into the next one.
+type migrationEntry struct {
+	From    uint32
+	To      uint32
+	Migrate func(old, new any) error
+}
+
+// LegacyVersion is an option of New naming the version of the payloads written
+// before any version was registered, which carry no header. Headers of the
+// instance then start with a two byte marker telling them apart from legacy
+// payloads, so legacy payloads starting with that marker can not be read.
+// Decoding from streams needs a reader with a Peek method, as bufio.Reader.
+//
+//	tb := tinybin.New(tinybin.LegacyVersion(1))
+type LegacyVersion uint32
+
+// RegisterVersion associates a message version with the type of the sample.
+// Versions are instance wide: each version number maps to exactly one type.
+// Once a version is registered every encoded payload starts with a header
+// carrying the version of its type, or 0 for types without a version.
+func (tb *TinyBin) RegisterVersion(version uint32, sample any) error {
+	if version == 0 {
+		return Err("RegisterVersion", D.Value, D.Zero, D.Not, D.Allowed)
+	}
+	rv := reflect.Indirect(reflect.ValueOf(sample))
+	if !rv.IsValid() {
+		return Err("RegisterVersion", D.Type, D.Nil)
+	}
+	typ := rv.Type()
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	for _, entry := range tb.versions {
+		if entry.Version == version || entry.Type == typ {
+			return Errf("version %d or type %s already registered", version, typ.String())
+		}
+	}
+
+	tb.versions = append(tb.versions, versionEntry{Version: version, Type: typ})
+	return nil
+}
+
+// RegisterMigration registers a function converting a value of version from
+// into a value of version to. The function receives pointers to values of the
+// registered types: old is fully decoded and new is zero-initialized. Decoding
+// an old payload runs the chain of migrations up to the target's version,
+// e.g. v1 -> v2 -> v3.
+func (tb *TinyBin) RegisterMigration(from, to uint32, migrate func(old, new any) error) error {
+	if from >= to {
+		return Errf("migration from version %d to %d must move forward", from, to)
+	}
+	if migrate == nil {
+		return Err("RegisterMigration", "func", D.Nil)
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	for _, entry := range tb.migrations {
+		if entry.From == from && entry.To == to {
+			return Errf("migration from version %d to %d already registered", from, to)
+		}
+	}
+
+	tb.migrations = append(tb.migrations, migrationEntry{From: from, To: to, Migrate: migrate})
+	return nil
+}
+
+// versioned reports whether any message version has been registered.
+func (tb *TinyBin) versioned() bool {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	return len(tb.versions) > 0
+}
+
+// versionOf returns the registered version of a type, or 0 if it has none.
+func (tb *TinyBin) versionOf(t reflect.Type) uint32 {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	for _, entry := range tb.versions {
+		if entry.Type == t {
+			return entry.Version
+		}
+	}
+	return 0
+}
+
+// typeOfVersion returns the type registered for a version.
+func (tb *TinyBin) typeOfVersion(version uint32) (reflect.Type, bool) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	for _, entry := range tb.versions {
+		if entry.Version == version {
+			return entry.Type, true
+		}
+	}
+	return nil, false
+}
+
+// migrationPath returns the migrations leading from one version to another,
+// taking the longest step that does not overshoot the target each time.
+func (tb *TinyBin) migrationPath(from, to uint32) ([]migrationEntry, error) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+
+	var path []migrationEntry
+	for from != to {
+		found := -1
+		for i, entry := range tb.migrations {
+			if entry.From == from && entry.To <= to && (found < 0 || entry.To > tb.migrations[found].To) {
+				found = i
+			}
+		}
+		if found < 0 {
+			return nil, Errf("no migration from version %d towards %d", from, to)
+		}
+		path = append(path, tb.migrations[found])
+		from = tb.migrations[found].To
+	}
+	return path, nil
+}
+
+// migrate decodes a payload of an older version and runs the migrations up to
+// the version of the target. It reports false when no migration is needed.
+func (d *decoder) migrate(version uint32, rv reflect.Value) (bool, error) {
+	if version == 0 {
+		return false, nil
+	}
+	current := d.tb.versionOf(rv.Type())
+	if current == 0 || version == current {
+		return false, nil
+	}
+	if version > current {
+		return true, Errf("payload version %d is newer than %s version %d", version, rv.Type().String(), current)
+	}
+
+	path, err := d.tb.migrationPath(version, current)
+	if err != nil {
+		return true, err
+	}
+
+	// Decode the payload into the type it was written with
+	src, ok := d.tb.typeOfVersion(version)
+	if !ok {
+		return true, Errf("no type registered for version %d", version)
+	}
+	c, err := d.scanToCache(src)
+	if err != nil {
+		return true, err
+	}
+	old := reflect.New(src)
+	if err = c.DecodeTo(d, old.Elem()); err != nil {
+		return true, err
+	}
+
+	// Run each step into a zero value, the last one of the type of the target
+	for i, step := range path {
+		typ := rv.Type()
+		if i < len(path)-1 {
+			if typ, ok = d.tb.typeOfVersion(step.To); !ok {
+				return true, Errf("no type registered for version %d", step.To)
+			}
+		}
+		next := reflect.New(typ)
+		if err = step.Migrate(old.Interface(), next.Interface()); err != nil {
+			return true, err
+		}
+		old = next
+	}
+	rv.Set(old.Elem())
+	return true, nil
+}
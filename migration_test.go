@@ -0,0 +1,168 @@
+package tinybin
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+type deviceConfigV1 struct {
+	Name    string
+	Celsius int
+}
+
+type deviceConfigV2 struct {
+	Name    string
+	Kelvin  float64
+	Enabled bool
+}
+
+type deviceConfigV3 struct {
+	Label   string
+	Kelvin  float64
+	Enabled bool
+}
+
+func newVersionedInstance(t *testing.T, args ...any) *TinyBin {
+	t.Helper()
+	tb := New(args...)
+	assertNoError(t, tb.RegisterVersion(1, deviceConfigV1{}))
+	assertNoError(t, tb.RegisterVersion(2, deviceConfigV2{}))
+	assertNoError(t, tb.RegisterVersion(3, deviceConfigV3{}))
+	assertNoError(t, tb.RegisterMigration(1, 2, func(old, new any) error {
+		o, n := old.(*deviceConfigV1), new.(*deviceConfigV2)
+		n.Name = o.Name
+		n.Kelvin = float64(o.Celsius) + 273.15
+		n.Enabled = true
+		return nil
+	}))
+	assertNoError(t, tb.RegisterMigration(2, 3, func(old, new any) error {
+		o, n := old.(*deviceConfigV2), new.(*deviceConfigV3)
+		n.Label = o.Name
+		n.Kelvin = o.Kelvin
+		n.Enabled = o.Enabled
+		return nil
+	}))
+	return tb
+}
+
+func TestMigrationChain(t *testing.T) {
+	tb := newVersionedInstance(t)
+
+	data, err := tb.Encode(&deviceConfigV1{Name: "boiler", Celsius: 20})
+	assertNoError(t, err)
+	assertEqual(t, byte(headerVersion), data[0])
+	assertEqual(t, byte(1), data[1])
+
+	var out deviceConfigV3
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, deviceConfigV3{Label: "boiler", Kelvin: 293.15, Enabled: true}, out)
+}
+
+func TestMigrationSameVersion(t *testing.T) {
+	tb := newVersionedInstance(t)
+	in := deviceConfigV3{Label: "pump", Kelvin: 300, Enabled: true}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+
+	var out deviceConfigV3
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+}
+
+func TestMigrationUnversionedType(t *testing.T) {
+	tb := newVersionedInstance(t)
+
+	data, err := tb.Encode(&basicStruct{Name: "John", Age: 25})
+	assertNoError(t, err)
+	assertEqual(t, byte(0), data[1])
+
+	var out basicStruct
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, "John", out.Name)
+}
+
+func TestMigrationErrors(t *testing.T) {
+	tb := newVersionedInstance(t)
+
+	if err := tb.RegisterVersion(0, basicStruct{}); err == nil {
+		t.Error("Expected error for version 0")
+	}
+	if err := tb.RegisterVersion(1, basicStruct{}); err == nil {
+		t.Error("Expected error for duplicated version")
+	}
+	if err := tb.RegisterMigration(3, 2, func(old, new any) error { return nil }); err == nil {
+		t.Error("Expected error for backwards migration")
+	}
+
+	// A newer payload can not be decoded into an older type
+	data, err := tb.Encode(&deviceConfigV3{Label: "x"})
+	assertNoError(t, err)
+	var out deviceConfigV1
+	if err := tb.Decode(data, &out); err == nil {
+		t.Error("Expected error decoding newer version")
+	}
+}
+
+func TestMigrationMissingStep(t *testing.T) {
+	tb := New()
+	assertNoError(t, tb.RegisterVersion(1, deviceConfigV1{}))
+	assertNoError(t, tb.RegisterVersion(3, deviceConfigV3{}))
+
+	data, err := tb.Encode(&deviceConfigV1{Name: "x"})
+	assertNoError(t, err)
+
+	var out deviceConfigV3
+	if err := tb.Decode(data, &out); err == nil {
+		t.Error("Expected error for missing migration")
+	}
+}
+
+func TestMigrationZeroesTarget(t *testing.T) {
+	tb := New()
+	assertNoError(t, tb.RegisterVersion(1, deviceConfigV1{}))
+	assertNoError(t, tb.RegisterVersion(2, deviceConfigV2{}))
+	assertNoError(t, tb.RegisterMigration(1, 2, func(old, new any) error {
+		new.(*deviceConfigV2).Name = old.(*deviceConfigV1).Name
+		return nil
+	}))
+
+	data, err := tb.Encode(&deviceConfigV1{Name: "fan"})
+	assertNoError(t, err)
+
+	// Fields the step leaves alone do not keep the previous values of the target
+	out := deviceConfigV2{Name: "old", Kelvin: 300, Enabled: true}
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, deviceConfigV2{Name: "fan"}, out)
+}
+
+func TestMigrationLegacyVersion(t *testing.T) {
+	// Payloads stored before versioning carry no header
+	legacy, err := New().Encode(&deviceConfigV1{Name: "x"})
+	assertNoError(t, err)
+
+	tb := newVersionedInstance(t, LegacyVersion(1))
+	var v1 deviceConfigV1
+	assertNoError(t, tb.Decode(legacy, &v1))
+	assertEqual(t, deviceConfigV1{Name: "x"}, v1)
+
+	var v3 deviceConfigV3
+	assertNoError(t, tb.Decode(legacy, &v3))
+	assertEqual(t, deviceConfigV3{Label: "x", Kelvin: 273.15, Enabled: true}, v3)
+
+	v3 = deviceConfigV3{}
+	assertNoError(t, tb.DecodeFrom(bufio.NewReader(bytes.NewReader(legacy)), &v3))
+	assertEqual(t, "x", v3.Label)
+
+	// Newer payloads start with the marker
+	data, err := tb.Encode(&deviceConfigV2{Name: "y", Kelvin: 300})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0xB1, 0x7E, headerVersion, 2}, data[:4])
+	v3 = deviceConfigV3{}
+	assertNoError(t, tb.Decode(data, &v3))
+	assertEqual(t, deviceConfigV3{Label: "y", Kelvin: 300}, v3)
+	v3 = deviceConfigV3{}
+	assertNoError(t, tb.DecodeFrom(bufio.NewReader(bytes.NewReader(data)), &v3))
+	assertEqual(t, "y", v3.Label)
+}
@@ -0,0 +1,198 @@
+package tinybin
+
+import (
+	"bufio"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Every log record starts with a fixed header: the little-endian payload length
+// followed by the little-endian CRC-32C of the payload.
+const logHeaderSize = 8
+
+// LogWriter appends encoded values as checksummed records to a file.
+type LogWriter struct {
+	tb     *TinyBin
+	file   *os.File
+	record []byte // reused record buffer
+}
+
+// OpenLog opens or creates a record log for appending. A torn record left at
+// the end of the file by an interrupted write is truncated before appending,
+// while a corrupted record followed by others makes it fail with ErrChecksum
+// and leave the file untouched.
+func (tb *TinyBin) OpenLog(path string) (*LogWriter, error) {
+	if _, err := RecoverLog(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &LogWriter{tb: tb, file: file}, nil
+}
+
+// Append encodes the value and appends it as a single record. The record is
+// written with one call so a crash leaves at most one torn record behind.
+func (w *LogWriter) Append(v any) error {
+	payload, err := w.tb.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	w.record = appendLogRecord(w.record[:0], payload)
+	_, err = w.file.Write(w.record)
+	return err
+}
+
+// Sync commits the appended records to stable storage.
+func (w *LogWriter) Sync() error {
+	return w.file.Sync()
+}
+
+// Close closes the underlying file.
+func (w *LogWriter) Close() error {
+	return w.file.Close()
+}
+
+// appendLogRecord appends the header and the payload of a record to dst.
+func appendLogRecord(dst, payload []byte) []byte {
+	l := uint32(len(payload))
+	crc := crc32.Checksum(payload, castagnoliTable)
+	dst = append(dst,
+		byte(l), byte(l>>8), byte(l>>16), byte(l>>24),
+		byte(crc), byte(crc>>8), byte(crc>>16), byte(crc>>24))
+	return append(dst, payload...)
+}
+
+// ------------------------------------------------------------------------------
+
+// LogReader iterates the records of a log file in the order they were appended.
+type LogReader struct {
+	tb      *TinyBin
+	file    *os.File
+	in      *bufio.Reader
+	header  [logHeaderSize]byte
+	payload []byte // reused payload buffer
+	offset  int64  // bytes of the file consumed so far
+}
+
+// OpenLogReader opens a record log for reading.
+func (tb *TinyBin) OpenLogReader(path string) (*LogReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &LogReader{tb: tb, file: file, in: bufio.NewReader(file)}, nil
+}
+
+// Next decodes the next record into v. It returns io.EOF after the last
+// record, io.ErrUnexpectedEOF for a torn final record and ErrChecksum for a
+// record whose content does not match its checksum.
+func (r *LogReader) Next(v any) error {
+	payload, err := r.readRecord()
+	if err != nil {
+		return err
+	}
+	return r.tb.Decode(payload, v)
+}
+
+// Close closes the underlying file.
+func (r *LogReader) Close() error {
+	return r.file.Close()
+}
+
+// readRecord reads and verifies the next record into the payload buffer.
+func (r *LogReader) readRecord() ([]byte, error) {
+	n, err := io.ReadFull(r.in, r.header[:])
+	r.offset += int64(n)
+	if err != nil {
+		if err == io.EOF && n == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	// The length is not verified yet, so the buffer only grows to what the
+	// rest of the file can hold
+	l := int64(readUint32(r.header[:4]))
+	if int64(cap(r.payload)) < l {
+		info, err := r.file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if l > info.Size()-r.offset {
+			return nil, io.ErrUnexpectedEOF
+		}
+		r.payload = make([]byte, l)
+	}
+	payload := r.payload[:l]
+	n, err = io.ReadFull(r.in, payload)
+	r.offset += int64(n)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if crc32.Checksum(payload, castagnoliTable) != readUint32(r.header[4:]) {
+		return nil, ErrChecksum
+	}
+	return payload, nil
+}
+
+// ------------------------------------------------------------------------------
+
+// RecoverLog validates the records of a log file and truncates the torn record
+// an interrupted write may leave at its end. It returns the number of bytes
+// removed. A record failing its checksum is only truncated when it is the last
+// one: with more bytes after it RecoverLog returns ErrChecksum and leaves the
+// file untouched, so valid records are never discarded.
+func RecoverLog(path string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	var header [logHeaderSize]byte
+	var buf []byte
+	var valid int64
+	in := bufio.NewReader(file)
+	for size-valid >= logHeaderSize {
+		if _, err = io.ReadFull(in, header[:]); err != nil {
+			return 0, err
+		}
+
+		// A length running past the end of the file can only be a torn record
+		l := int64(readUint32(header[:4]))
+		if l > size-valid-logHeaderSize {
+			break
+		}
+		if int64(cap(buf)) < l {
+			buf = make([]byte, l)
+		}
+		payload := buf[:l]
+		if _, err = io.ReadFull(in, payload); err != nil {
+			return 0, err
+		}
+		if crc32.Checksum(payload, castagnoliTable) != readUint32(header[4:]) {
+			if valid+logHeaderSize+l < size {
+				return 0, ErrChecksum
+			}
+			break
+		}
+		valid += logHeaderSize + l
+	}
+
+	if valid == size {
+		return 0, nil
+	}
+	return size - valid, file.Truncate(valid)
+}
@@ -0,0 +1,144 @@
+package tinybin
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestLog(t *testing.T, tb *TinyBin, path string, names ...string) {
+	t.Helper()
+	w, err := tb.OpenLog(path)
+	assertNoError(t, err)
+	for i, name := range names {
+		assertNoError(t, w.Append(&basicStruct{Name: name, Age: i}))
+	}
+	assertNoError(t, w.Sync())
+	assertNoError(t, w.Close())
+}
+
+func readTestLog(t *testing.T, tb *TinyBin, path string) ([]string, error) {
+	t.Helper()
+	r, err := tb.OpenLogReader(path)
+	assertNoError(t, err)
+	defer r.Close()
+
+	var names []string
+	for {
+		var v basicStruct
+		if err := r.Next(&v); err != nil {
+			if err == io.EOF {
+				return names, nil
+			}
+			return names, err
+		}
+		names = append(names, v.Name)
+	}
+}
+
+func TestRecordLogAppendAndRead(t *testing.T) {
+	tb := New()
+	path := filepath.Join(t.TempDir(), "readings.log")
+
+	writeTestLog(t, tb, path, "a", "b")
+	writeTestLog(t, tb, path, "c")
+
+	names, err := readTestLog(t, tb, path)
+	assertNoError(t, err)
+	assertEqual(t, []string{"a", "b", "c"}, names)
+}
+
+func TestRecordLogTornTail(t *testing.T) {
+	tb := New()
+	path := filepath.Join(t.TempDir(), "readings.log")
+	writeTestLog(t, tb, path, "a", "b", "c")
+
+	// Simulate a power cut in the middle of the last record
+	info, err := os.Stat(path)
+	assertNoError(t, err)
+	assertNoError(t, os.Truncate(path, info.Size()-3))
+
+	names, err := readTestLog(t, tb, path)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	assertEqual(t, []string{"a", "b"}, names)
+
+	removed, err := RecoverLog(path)
+	assertNoError(t, err)
+	if removed == 0 {
+		t.Error("Expected torn record to be removed")
+	}
+
+	// Appending after recovery keeps the log readable
+	writeTestLog(t, tb, path, "d")
+	names, err = readTestLog(t, tb, path)
+	assertNoError(t, err)
+	assertEqual(t, []string{"a", "b", "d"}, names)
+}
+
+func TestRecordLogCorruptedRecord(t *testing.T) {
+	tb := New()
+	path := filepath.Join(t.TempDir(), "readings.log")
+	writeTestLog(t, tb, path, "a", "b")
+
+	data, err := os.ReadFile(path)
+	assertNoError(t, err)
+	data[len(data)-2] ^= 0xFF
+	assertNoError(t, os.WriteFile(path, data, 0o644))
+
+	names, err := readTestLog(t, tb, path)
+	if err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+	assertEqual(t, []string{"a"}, names)
+
+	// OpenLog recovers before appending
+	writeTestLog(t, tb, path, "c")
+	names, err = readTestLog(t, tb, path)
+	assertNoError(t, err)
+	assertEqual(t, []string{"a", "c"}, names)
+}
+
+func TestRecordLogCorruptedMiddleRecord(t *testing.T) {
+	tb := New()
+	path := filepath.Join(t.TempDir(), "readings.log")
+	writeTestLog(t, tb, path, "a", "b", "c")
+
+	// Flip a bit in the payload of the first record
+	data, err := os.ReadFile(path)
+	assertNoError(t, err)
+	data[logHeaderSize] ^= 0x01
+	assertNoError(t, os.WriteFile(path, data, 0o644))
+
+	if _, err := RecoverLog(path); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+	if _, err := tb.OpenLog(path); err != ErrChecksum {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+
+	// The records after the corrupted one are kept
+	kept, err := os.ReadFile(path)
+	assertNoError(t, err)
+	assertEqualBytes(t, data, kept)
+}
+
+func TestRecordLogCorruptedLength(t *testing.T) {
+	tb := New()
+	path := filepath.Join(t.TempDir(), "readings.log")
+	writeTestLog(t, tb, path, "a")
+
+	// A length of 4 GiB must not be allocated
+	data, err := os.ReadFile(path)
+	assertNoError(t, err)
+	data[0], data[1], data[2], data[3] = 0xFF, 0xFF, 0xFF, 0xFF
+	assertNoError(t, os.WriteFile(path, data, 0o644))
+
+	names, err := readTestLog(t, tb, path)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	assertEqualInt(t, 0, len(names))
+}
@@ -0,0 +1,605 @@
+package tinybin
+
+import (
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// DecodeWithWriterSchema decodes a payload that was encoded with the writer
+// schema into a target whose type may have evolved since. Fields are matched
+// by name: fields unknown to the target are skipped and fields missing from
+// the payload keep their zero value. Compatible representations are converted
+// on the fly: integer widths, float32 to float64, []byte and string, T and *T.
+func (tb *TinyBin) DecodeWithWriterSchema(data []byte, writer Schema, target any) error {
+	rv := reflect.Indirect(reflect.ValueOf(target))
+	if !rv.CanAddr() {
+		return Err(D.Binary, "decoder", D.Required, D.Type, D.Pointer)
+	}
+
+	c, err := tb.resolve(&writer, rv.Type())
+	if err != nil {
+		return err
+	}
+
+	if tb.checksum != ChecksumNone {
+		if data, err = tb.openChecked(data); err != nil {
+			return err
+		}
+	}
+
+	d := tb.decoders.Get().(*decoder)
+	d.Reset(data, tb)
+	body := d
+	if tb.usesHeader() {
+		var h header
+		if h, err = d.readHeader(); err == nil {
+			body, err = d.payload(h)
+		}
+	}
+	if err == nil {
+		err = c.DecodeTo(body, rv)
+	}
+	tb.decoders.Put(d)
+	return err
+}
+
+// resolve builds a decode-only codec that reads what the writer schema describes
+// and stores it into values of the reader type.
+func (tb *TinyBin) resolve(w *Schema, t reflect.Type) (Codec, error) {
+	r := describe(t)
+	if sameWire(w, &r) {
+		return tb.scanToCache(t)
+	}
+
+	// Constants are checked after resolving the untagged field
+	if _, ok := w.constant(); ok {
+		return tb.resolveConst(w, t)
+	}
+
+	// Byte order tags wrap the resolution of the untagged field
+	if w.byteOrder() != orderDefault {
+		return tb.resolveByteOrder(w, t)
+	}
+
+	// Fixed width integers decode into any integer of the same signedness
+	if w.wire() == wireFixed {
+		return resolveFixed(w, t)
+	}
+
+	// Sized and zero terminated fields decode into any string or []byte
+	if ww := w.wire(); ww == wireSized || ww == wireCString {
+		return resolveText(w, t)
+	}
+
+	// Columnar, delta, XOR float and counted fields are only decoded by
+	// identical fields, and sealed fields need their struct to be opened
+	ww := w.wire()
+	switch {
+	case ww == wireColumnar, ww == wireDelta, ww == wireXORFloat, ww == wireCounted, ww == wireBits,
+		tagOptions(w.Tag).Has("encrypt"):
+		return nil, Err(D.Field, w.Name, D.Not, D.Assignable, "from", w.Tag)
+	}
+
+	// Pointer nullability changes wrap the resolution of the pointed type
+	if ww == wirePointer && t.Kind() != reflect.Ptr {
+		elemCodec, err := tb.resolve(w.Elem, t)
+		if err != nil {
+			return nil, err
+		}
+		return &pointerToValueCodec{elemCodec: elemCodec}, nil
+	}
+	if ww != wirePointer && t.Kind() == reflect.Ptr {
+		elemCodec, err := tb.resolve(w, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &valueToPointerCodec{elemCodec: elemCodec}, nil
+	}
+
+	switch ww {
+	case wirePointer:
+		elemCodec, err := tb.resolve(w.Elem, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &reflectPointerCodec{elemCodec: elemCodec}, nil
+
+	case wireVarint:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if tb.fixedInts() {
+				return &fixedCodec{width: fixedSize(w.Kind), signed: true}, nil
+			}
+			return new(resolvedVarintCodec), nil
+		}
+
+	case wireUvarint:
+		switch t.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if tb.fixedInts() {
+				return &fixedCodec{width: fixedSize(w.Kind), signed: false}, nil
+			}
+			return new(resolvedVaruintCodec), nil
+		}
+
+	case wireBool:
+		if t.Kind() == reflect.Bool {
+			return new(boolCodec), nil
+		}
+
+	case wireFloat32:
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return new(float32Codec), nil
+		}
+
+	case wireFloat64:
+		if t.Kind() == reflect.Float64 {
+			return new(float64Codec), nil
+		}
+
+	case wireBytes:
+		if r.Marshaler {
+			return new(binaryMarshalerCodec), nil
+		}
+		if r.wire() == wireBytes {
+			return &resolvedBytesCodec{fromString: w.dictString()}, nil
+		}
+
+	case wireSlice:
+		if t.Kind() == reflect.Slice && tb.adaptiveInts() && isIntWire(w.Elem) {
+			// The integer slice codecs read every adaptive encoding
+			if e := describe(t.Elem()); e.wire() == w.Elem.wire() {
+				return tb.scanToCache(t)
+			}
+			break
+		}
+		if t.Kind() == reflect.Slice {
+			elemCodec, err := tb.resolve(w.Elem, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return &resolvedSliceCodec{elemCodec: elemCodec}, nil
+		}
+
+	case wireArray:
+		if t.Kind() == reflect.Array && t.Len() == w.Len {
+			elemCodec, err := tb.resolve(w.Elem, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return &reflectArrayCodec{elemCodec: elemCodec}, nil
+		}
+
+	case wireStruct:
+		if t.Kind() == reflect.Struct {
+			return tb.resolveStruct(w, t)
+		}
+	}
+
+	return nil, Err(D.Type, t.String(), D.Not, D.Assignable, "from", w.Type)
+}
+
+// resolveStruct matches the writer fields by name against the reader fields.
+func (tb *TinyBin) resolveStruct(w *Schema, t reflect.Type) (Codec, error) {
+	c, err := tb.scanToCache(t)
+	if err != nil {
+		return nil, err
+	}
+	readerFields, _ := c.(*reflectStructCodec)
+
+	out := make(resolvedStructCodec, 0, len(w.Fields))
+	pads, _, _, _ := w.structLayout()
+	for i := 0; i < len(w.Fields); i++ {
+		wf := &w.Fields[i]
+		if pads != nil && pads[i] > 0 {
+			out = append(out, fieldCodec{Index: -1, Codec: padCodec(pads[i])})
+		}
+		if wf.bitWidth() > 0 {
+			end := bitRun(w.Fields, i)
+			group, err := resolveBitGroup(w.Fields[i:end], t)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, fieldCodec{Index: -1, Codec: group})
+			i = end - 1
+			continue
+		}
+
+		fc, err := tb.resolveField(w.Type, wf, t, readerFields)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fc)
+	}
+	if pads != nil && pads[len(w.Fields)] > 0 {
+		out = append(out, fieldCodec{Index: -1, Codec: padCodec(pads[len(w.Fields)])})
+	}
+	return out, nil
+}
+
+// resolveField resolves a field of the writer struct type owner, other than a
+// bitfield, against the fields of the reader struct.
+func (tb *TinyBin) resolveField(owner string, wf *Schema, t reflect.Type, readerFields *reflectStructCodec) (fieldCodec, error) {
+	field, found := t.FieldByName(wf.Name)
+	found = found && len(field.Index) == 1 && field.Tag.Get("binary") != "-"
+	encrypted := tagOptions(wf.Tag).Has("encrypt")
+	if found && readerFields != nil && !encrypted {
+		r := describe(field.Type)
+		r.Tag = field.Tag.Get("binary")
+		if sameWire(wf, &r) {
+			// Identical fields reuse the codec scanned for the reader struct
+			for _, fc := range *readerFields {
+				if fc.field() == field.Index[0] {
+					return fc, nil
+				}
+			}
+		}
+	}
+
+	if cond, ok := wf.condition(); ok {
+		return tb.resolveConditional(owner, wf, cond, t, readerFields)
+	}
+	if found && encrypted {
+		return tb.resolveEncrypted(owner, wf, field)
+	}
+
+	if !found {
+		if wf.wire() == wireCounted {
+			skipper, err := resolveCountedSkip(wf, t)
+			return fieldCodec{Index: -1, Codec: skipper}, err
+		}
+		return fieldCodec{Index: -1, Codec: &skipCodec{schema: wf}}, nil
+	}
+
+	codec, err := tb.resolve(wf, field.Type)
+	return fieldCodec{Index: field.Index[0], Codec: codec}, err
+}
+
+// resolveBitGroup matches a run of writer bitfields by name against the reader
+// fields, which may be bitfields or plain fields of the same kind class.
+func resolveBitGroup(fields []Schema, t reflect.Type) (bitGroupCodec, error) {
+	group := schemaBitGroup(fields)
+	for i := range group {
+		field, ok := t.FieldByName(fields[i].Name)
+		if !ok || len(field.Index) != 1 || field.Tag.Get("binary") == "-" {
+			continue
+		}
+
+		reader := bitField{Kind: Kind(field.Type.Kind())}
+		switch {
+		case group[i].Kind == K.Bool && reader.Kind == K.Bool,
+			group[i].signed() && reader.signed(),
+			group[i].unsigned() && reader.unsigned():
+			group[i].Index = field.Index[0]
+		default:
+			return nil, Err(D.Type, field.Type.String(), D.Not, D.Assignable, "from", fields[i].Type)
+		}
+	}
+	return group, nil
+}
+
+// sameWire reports whether two schemas describe exactly the same encoding.
+func sameWire(a, b *Schema) bool {
+	if a.Kind != b.Kind || a.Tag != b.Tag || a.Len != b.Len || a.Marshaler != b.Marshaler {
+		return false
+	}
+	if (a.Elem == nil) != (b.Elem == nil) || (a.Elem != nil && !sameWire(a.Elem, b.Elem)) {
+		return false
+	}
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		if a.Fields[i].Name != b.Fields[i].Name || !sameWire(&a.Fields[i], &b.Fields[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ------------------------------------------------------------------------------
+
+var errDecodeOnly = Err(D.Binary, "resolved codec", D.Not, D.Supported, "for encoding")
+
+// resolvedStructCodec decodes writer fields in wire order, an Index of -1 marks
+// a field that only exists in the writer schema.
+type resolvedStructCodec []fieldCodec
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c resolvedStructCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c resolvedStructCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	for _, fc := range c {
+		if err = fc.Codec.DecodeTo(d, fc.value(rv)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+type resolvedSliceCodec struct {
+	elemCodec Codec // The codec converting the writer elements
+}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *resolvedSliceCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *resolvedSliceCodec) DecodeTo(d *decoder, rv reflect.Value) (err error) {
+	var l uint64
+	if l, err = d.ReadUvarint(); err != nil || l == 0 {
+		return err
+	}
+
+	rv.Set(reflect.MakeSlice(rv.Type(), int(l), int(l)))
+	for i := 0; i < int(l); i++ {
+		if err = c.elemCodec.DecodeTo(d, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// pointerToValueCodec reads an optional writer value into a non-pointer reader
+// field, a nil pointer leaves the zero value.
+type pointerToValueCodec struct {
+	elemCodec Codec
+}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *pointerToValueCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *pointerToValueCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	isNil, err := d.ReadBool()
+	if err != nil {
+		return err
+	}
+	if isNil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	return c.elemCodec.DecodeTo(d, rv)
+}
+
+// valueToPointerCodec reads a plain writer value into a pointer reader field.
+type valueToPointerCodec struct {
+	elemCodec Codec
+}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *valueToPointerCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *valueToPointerCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if rv.IsNil() {
+		rv.Set(reflect.New(rv.Type().Elem()))
+	}
+	return c.elemCodec.DecodeTo(d, rv.Elem())
+}
+
+// ------------------------------------------------------------------------------
+
+// resolvedVarintCodec reads a signed varint of any width into a signed integer,
+// failing if the value does not fit the reader type.
+type resolvedVarintCodec struct{}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *resolvedVarintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *resolvedVarintCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	v, err := d.ReadVarint()
+	if err != nil {
+		return err
+	}
+	if rv.OverflowInt(v) {
+		return Errf("value %d overflows %s", v, rv.Type().String())
+	}
+	rv.SetInt(v)
+	return nil
+}
+
+// resolvedVaruintCodec reads an unsigned varint of any width into an unsigned
+// integer, failing if the value does not fit the reader type.
+type resolvedVaruintCodec struct{}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *resolvedVaruintCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *resolvedVaruintCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	v, err := d.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	if rv.OverflowUint(v) {
+		return Errf("value %d overflows %s", v, rv.Type().String())
+	}
+	rv.SetUint(v)
+	return nil
+}
+
+// resolvedBytesCodec reads length-prefixed bytes into either a string or a
+// byte slice.
+type resolvedBytesCodec struct {
+	fromString bool // The writer type is a string, which may use the string table
+}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *resolvedBytesCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *resolvedBytesCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	if c.fromString && d.dict != nil {
+		s, err := d.readDictString()
+		if err != nil {
+			return err
+		}
+		if rv.Kind() == reflect.String {
+			rv.SetString(s)
+		} else if len(s) > 0 {
+			rv.SetBytes([]byte(s))
+		}
+		return nil
+	}
+
+	b, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+	if rv.Kind() == reflect.String {
+		rv.SetString(string(b))
+		return nil
+	}
+	if len(b) > 0 {
+		rv.SetBytes(append([]byte(nil), b...))
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------
+
+// skipCodec consumes a value described by the writer schema without storing it.
+type skipCodec struct {
+	schema *Schema
+}
+
+// Encode is not supported, resolved codecs only read older payloads.
+func (c *skipCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	return errDecodeOnly
+}
+
+// Decode reads and discards the value.
+func (c *skipCodec) DecodeTo(d *decoder, _ reflect.Value) error {
+	return skip(d, c.schema)
+}
+
+func skip(d *decoder, s *Schema) (err error) {
+	if _, ok := s.constant(); ok {
+		_, err = decodeValue(d, s)
+		return err
+	}
+
+	switch s.wire() {
+	case wireBool:
+		_, err = d.ReadBool()
+	case wireVarint:
+		if d.fixedInts() {
+			_, err = d.Slice(fixedSize(s.Kind))
+			break
+		}
+		_, err = d.ReadVarint()
+	case wireUvarint:
+		if d.fixedInts() {
+			_, err = d.Slice(fixedSize(s.Kind))
+			break
+		}
+		_, err = d.ReadUvarint()
+	case wireFixed:
+		_, err = d.Slice(s.fixedWidth())
+	case wireSized:
+		_, err = d.Slice(s.byteSize())
+	case wireCString:
+		_, err = readCString(d)
+	case wireFloat32:
+		_, err = d.Slice(4)
+	case wireFloat64:
+		_, err = d.Slice(8)
+	case wireBytes:
+		if d.dict != nil && s.dictString() {
+			_, err = d.readDictString()
+			break
+		}
+		_, err = d.ReadSlice()
+	case wireDelta:
+		err = decodeIntItems(d, s, new(Value))
+	case wireXORFloat:
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil && l > 0 {
+			_, err = d.ReadSlice()
+		}
+	case wireSlice:
+		if d.adaptiveInts() && isIntWire(s.Elem) {
+			err = decodeIntItems(d, s, new(Value))
+			break
+		}
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil {
+			for i := 0; i < int(l) && err == nil; i++ {
+				err = skip(d, s.Elem)
+			}
+		}
+	case wireArray:
+		for i := 0; i < s.Len && err == nil; i++ {
+			err = skip(d, s.Elem)
+		}
+	case wirePointer:
+		var isNil bool
+		if isNil, err = d.ReadBool(); err == nil && !isNil {
+			err = skip(d, s.Elem)
+		}
+	case wireStruct:
+		if s.hasLinkedFields() {
+			_, err = decodeValue(d, s)
+			break
+		}
+		pads := d.structPads(s)
+		for i := 0; i < len(s.Fields) && err == nil; i = bitRun(s.Fields, i) {
+			if err = d.skipPad(pads, i); err == nil {
+				err = skipField(d, s.Fields, i)
+			}
+		}
+		if err == nil {
+			err = d.skipPad(pads, len(s.Fields))
+		}
+	case wireBits:
+		_, err = decodeBitValues(d, []Schema{*s})
+	case wireColumnar:
+		if s.Elem.hasLinkedFields() {
+			_, err = decodeValue(d, s)
+			break
+		}
+		var l uint64
+		if l, err = d.ReadUvarint(); err == nil {
+			for f := 0; f < len(s.Elem.Fields) && err == nil; f = bitRun(s.Elem.Fields, f) {
+				for i := 0; i < int(l) && err == nil; i++ {
+					err = skipField(d, s.Elem.Fields, f)
+				}
+			}
+		}
+	default:
+		err = Err(D.Type, s.Type, D.Not, D.Supported)
+	}
+	return err
+}
+
+// skipField skips field i of a struct, along with the bitfields packed with it.
+func skipField(d *decoder, fields []Schema, i int) (err error) {
+	if fields[i].bitWidth() > 0 {
+		_, err = decodeBitValues(d, fields[i:bitRun(fields, i)])
+		return err
+	}
+	return skip(d, &fields[i])
+}
@@ -0,0 +1,102 @@
+package tinybin
+
+import (
+	"testing"
+)
+
+type readingV1 struct {
+	ID      int32
+	Label   []byte
+	Value   float32
+	Unit    string
+	Offset  *int16
+	Samples []uint16
+	Retired bool
+}
+
+type readingV2 struct {
+	ID      int64
+	Label   string
+	Value   float64
+	Unit    *string
+	Offset  int64
+	Samples []*uint64
+	Comment string
+}
+
+func TestDecodeWithWriterSchema(t *testing.T) {
+	tb := New()
+	writer, err := tb.Schema(readingV1{})
+	assertNoError(t, err)
+
+	offset := int16(-7)
+	data, err := tb.Encode(&readingV1{
+		ID:      42,
+		Label:   []byte("probe"),
+		Value:   1.5,
+		Unit:    "C",
+		Offset:  &offset,
+		Samples: []uint16{1, 2, 300},
+		Retired: true,
+	})
+	assertNoError(t, err)
+
+	var out readingV2
+	assertNoError(t, tb.DecodeWithWriterSchema(data, writer, &out))
+
+	assertEqual(t, int64(42), out.ID)
+	assertEqual(t, "probe", out.Label)
+	assertEqual(t, float64(1.5), out.Value)
+	assertEqual(t, "C", *out.Unit)
+	assertEqual(t, int64(-7), out.Offset)
+	assertEqualInt(t, 3, len(out.Samples))
+	assertEqual(t, uint64(300), *out.Samples[2])
+	assertEqual(t, "", out.Comment)
+}
+
+func TestDecodeWithWriterSchemaNilPointer(t *testing.T) {
+	tb := New()
+	writer, err := tb.Schema(readingV1{})
+	assertNoError(t, err)
+
+	data, err := tb.Encode(&readingV1{ID: 1})
+	assertNoError(t, err)
+
+	out := readingV2{Offset: 99}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, writer, &out))
+	assertEqual(t, int64(0), out.Offset)
+}
+
+func TestDecodeWithWriterSchemaOverflow(t *testing.T) {
+	type wide struct{ V int64 }
+	type narrow struct{ V int8 }
+
+	tb := New()
+	writer, err := tb.Schema(wide{})
+	assertNoError(t, err)
+
+	data, err := tb.Encode(&wide{V: 1000})
+	assertNoError(t, err)
+
+	var out narrow
+	if err := tb.DecodeWithWriterSchema(data, writer, &out); err == nil {
+		t.Error("Expected overflow error")
+	}
+}
+
+func TestDecodeWithWriterSchemaIncompatible(t *testing.T) {
+	type before struct{ V string }
+	type after struct{ V int }
+
+	tb := New()
+	writer, err := tb.Schema(before{})
+	assertNoError(t, err)
+
+	data, err := tb.Encode(&before{V: "x"})
+	assertNoError(t, err)
+
+	var out after
+	if err := tb.DecodeWithWriterSchema(data, writer, &out); err == nil {
+		t.Error("Expected incompatible type error")
+	}
+}
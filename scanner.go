@@ -35,11 +35,7 @@ func scanType(t reflect.Type) (Codec, error) {
 	}
 
 	// Check if the type or a pointer to it implements the marshaling interfaces.
-	pt := reflect.PtrTo(t)
-	if t.Implements(binaryMarshalerType) && pt.Implements(binaryUnmarshalerType) {
-		return new(binaryMarshalerCodec), nil
-	}
-	if pt.Implements(binaryMarshalerType) && pt.Implements(binaryUnmarshalerType) {
+	if isBinaryMarshaler(t) {
 		return new(binaryMarshalerCodec), nil
 	}
 
@@ -115,9 +111,35 @@ func scanType(t reflect.Type) (Codec, error) {
 	case reflect.Struct:
 		s := scanStruct(t)
 		v := make(reflectStructCodec, 0, len(s.fields))
-		for _, i := range s.fields {
+		schema := describe(t)
+		pads, _, _, _ := schema.structLayout()
+		var group bitGroupCodec
+		for n, i := range s.fields {
 			field := t.Field(i)
-			codec, err := scanType(field.Type)
+
+			// Padding aligns fields on instances with CLayout alignment
+			if pads != nil && pads[n] > 0 {
+				v = append(v, fieldCodec{Index: -1, Codec: padCodec(pads[n])})
+			}
+
+			// Consecutive bitfields share bytes, the group reads the whole struct
+			if width, ok := tagOptions(field.Tag.Get("binary")).Get("bits"); ok {
+				// The group writes the bits itself, no other option applies
+				if rest := tagOptions(field.Tag.Get("binary")).without("bits"); rest != "" {
+					return nil, Err("bits", field.Name, rest, D.Not, D.Supported)
+				}
+				f, err := newBitField(field, i, width)
+				if err != nil {
+					return nil, err
+				}
+				if group = append(group, f); n == len(s.fields)-1 || !isBitField(t.Field(s.fields[n+1])) {
+					v = append(v, fieldCodec{Index: -1, Codec: group})
+					group = nil
+				}
+				continue
+			}
+
+			codec, err := scanField(t, field)
 			if err != nil {
 				return nil, err
 			}
@@ -128,6 +150,15 @@ func scanType(t reflect.Type) (Codec, error) {
 				Codec: codec,
 			})
 		}
+		if pads != nil && pads[len(s.fields)] > 0 {
+			v = append(v, fieldCodec{Index: -1, Codec: padCodec(pads[len(s.fields)])})
+		}
+		if err := linkCounts(t, v); err != nil {
+			return nil, err
+		}
+		if err := linkConditions(t, v); err != nil {
+			return nil, err
+		}
 
 		return &v, nil
 
@@ -148,6 +179,182 @@ func scanType(t reflect.Type) (Codec, error) {
 	return nil, Err(D.Type, D.Binary, t.String(), D.Not, D.Supported)
 }
 
+// isBinaryMarshaler reports whether the type or a pointer to it implements
+// both encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+func isBinaryMarshaler(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	if t.Implements(binaryMarshalerType) && pt.Implements(binaryUnmarshalerType) {
+		return true
+	}
+	return pt.Implements(binaryMarshalerType) && pt.Implements(binaryUnmarshalerType)
+}
+
+// scanField scans the type of a field of the struct type owner and applies the
+// options of its `binary` tag to the resulting codec.
+func scanField(owner reflect.Type, field reflect.StructField) (Codec, error) {
+	codec, err := scanType(field.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := tagOptions(field.Tag.Get("binary"))
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	if name, ok := opts.Get("len"); ok {
+		// The count replaces the whole encoding, only the condition applies
+		if rest := opts.without("len", "if"); rest != "" {
+			return nil, Err("len", field.Name, rest, D.Not, D.Supported)
+		}
+		return newCountedCodec(field, name)
+	}
+	if fixed, err := newFixedCodec(field.Type, opts); err != nil {
+		return nil, err
+	} else if fixed != nil {
+		codec = fixed
+	}
+	if sized, err := newSizedCodec(field, opts); err != nil {
+		return nil, err
+	} else if sized != nil {
+		codec = sized
+	}
+	if opts.Has("delta") {
+		if codec, err = newDeltaCodec(field.Type); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Has("xorfloat") {
+		if codec, err = newXORFloatCodec(field.Type); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Has("columnar") {
+		if codec, err = newColumnarCodec(field.Type); err != nil {
+			return nil, err
+		}
+	}
+	if order := tagByteOrder(opts); order != orderDefault {
+		fixed := fixedTag(opts, Kind(field.Type.Kind())) > 0
+		if codec, err = newByteOrderCodec(field.Type, codec, order, fixed); err != nil {
+			return nil, err
+		}
+	}
+	if literal, ok := opts.Get("const"); ok {
+		if codec, err = newConstCodec(field.Type, codec, field.Name, literal); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Has("encrypt") {
+		aad := owner.String() + "." + field.Name + " " + field.Type.String()
+		codec = &encryptedCodec{elemCodec: codec, name: field.Name, aad: aad}
+	}
+	return codec, nil
+}
+
+// isBitField reports whether the field is tagged with `binary:"bits=N"`.
+func isBitField(field reflect.StructField) bool {
+	return tagOptions(field.Tag.Get("binary")).Has("bits")
+}
+
+// tagOptions are the comma separated options of a `binary` struct tag, either
+// flags (`binary:"encrypt"`) or key=value pairs.
+type tagOptions string
+
+// Known options of the `binary` tag, flags and key=value pairs.
+var (
+	tagFlags = []string{"encrypt", "columnar", "delta", "xorfloat", "fixed", "fixed16", "fixed32", "fixed64", "be", "le", "cstring"}
+	tagPairs = []string{"bits", "len", "size", "const", "if"}
+)
+
+// check returns an error for the first option that is not known, or is not in
+// its flag or key=value form, since a typo would silently change the wire
+// format.
+func (o tagOptions) check() error {
+	s := string(o)
+	for s != "" {
+		opt := s
+		if i := Index(s, ","); i >= 0 {
+			opt, s = s[:i], s[i+1:]
+		} else {
+			s = ""
+		}
+
+		key, pair := opt, false
+		if i := Index(opt, "="); i >= 0 {
+			key, pair = opt[:i], true
+		}
+		known := false
+		for _, name := range tagFlags {
+			known = known || !pair && key == name
+		}
+		for _, name := range tagPairs {
+			known = known || pair && key == name
+		}
+		if !known {
+			return Err(D.Binary, "tag", opt, D.Not, D.Supported)
+		}
+	}
+	return nil
+}
+
+// Has reports whether the option is present.
+func (o tagOptions) Has(name string) bool {
+	_, ok := o.Get(name)
+	return ok
+}
+
+// Get returns the value of a key=value option, or "" for a flag.
+func (o tagOptions) Get(name string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		opt := s
+		if i := Index(s, ","); i >= 0 {
+			opt, s = s[:i], s[i+1:]
+		} else {
+			s = ""
+		}
+
+		key, value := opt, ""
+		if i := Index(opt, "="); i >= 0 {
+			key, value = opt[:i], opt[i+1:]
+		}
+		if key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// without returns the options other than the named ones.
+func (o tagOptions) without(names ...string) string {
+	var out string
+	s := string(o)
+	for s != "" {
+		opt := s
+		if i := Index(s, ","); i >= 0 {
+			opt, s = s[:i], s[i+1:]
+		} else {
+			s = ""
+		}
+
+		key := opt
+		if i := Index(opt, "="); i >= 0 {
+			key = opt[:i]
+		}
+		keep := true
+		for _, name := range names {
+			keep = keep && key != name
+		}
+		if keep && out != "" {
+			out += ","
+		}
+		if keep {
+			out += opt
+		}
+	}
+	return out
+}
+
 type scannedStruct struct {
 	fields []int
 }
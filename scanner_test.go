@@ -71,3 +71,31 @@ type Strings struct {
 	Data map[uint64][]byte
 }
 */
+
+func TestScannerUnknownTagOptions(t *testing.T) {
+	tb := New()
+	for _, in := range []any{
+		&struct {
+			V []int64 `binary:"detla"`
+		}{},
+		&struct {
+			V uint32 `binary:"fixed, be"`
+		}{},
+		&struct {
+			V uint32 `binary:"fixed=4"`
+		}{},
+		&struct {
+			V string `binary:"size"`
+		}{},
+	} {
+		if _, err := tb.Encode(in); err == nil {
+			t.Errorf("Expected error for unknown tag option in %T", in)
+		}
+	}
+
+	data, err := tb.Encode(&struct {
+		V uint32 `binary:"fixed,be"`
+	}{V: 1})
+	assertNoError(t, err)
+	assertEqualBytes(t, []byte{0, 0, 0, 1}, data)
+}
@@ -0,0 +1,249 @@
+package tinybin
+
+import (
+	"bytes"
+	"reflect"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Schema describes how a type is laid out on the wire, following the same
+// rules scanType uses to build its codecs. Schemas are plain values: they can
+// be stored (they implement encoding.BinaryMarshaler) and compared later with
+// CheckCompatibility.
+type Schema struct {
+	Name      string   // Field name when the schema describes a struct field
+	Type      string   // Go type name, informational only
+	Kind      Kind     // Go kind of the described type
+	Tag       string   // Value of the `binary` struct tag of the field
+	Len       int      // Number of elements of a fixed array
+	Marshaler bool     // Encoded through encoding.BinaryMarshaler
+	Elem      *Schema  // Element of pointers, slices and arrays
+	Fields    []Schema // Struct fields in wire order
+}
+
+// Schema returns the wire schema of the value's type.
+func (tb *TinyBin) Schema(v any) (Schema, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return Schema{}, Errf("cannot describe nil value")
+	}
+
+	// Scanning validates that every part of the type is supported
+	typ := rv.Type()
+	if _, err := tb.scanToCache(typ); err != nil {
+		return Schema{}, err
+	}
+
+	return describe(typ), nil
+}
+
+// describe builds the schema of a type that scanType accepted.
+func describe(t reflect.Type) Schema {
+	s := Schema{Type: t.String(), Kind: Kind(t.Kind())}
+	if isBinaryMarshaler(t) {
+		s.Marshaler = true
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		elem := describe(t.Elem())
+		s.Elem = &elem
+	case reflect.Array:
+		elem := describe(t.Elem())
+		s.Elem = &elem
+		s.Len = t.Len()
+	case reflect.Struct:
+		for _, i := range scanStruct(t).fields {
+			field := t.Field(i)
+			fs := describe(field.Type)
+			fs.Name = field.Name
+			fs.Tag = field.Tag.Get("binary")
+			s.Fields = append(s.Fields, fs)
+		}
+	}
+	return s
+}
+
+// field returns the position of the named field, or -1 if there is none.
+func (s *Schema) field(name string) int {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ------------------------------------------------------------------------------
+
+// wireKind groups the Go kinds that share the same representation on the wire.
+type wireKind uint8
+
+const (
+	wireInvalid wireKind = iota
+	wireBool
+	wireVarint
+	wireUvarint
+	wireFloat32
+	wireFloat64
+	wireBytes // varint length followed by raw bytes
+	wireSlice
+	wireArray
+	wirePointer
+	wireStruct
+	wireColumnar // slice of structs written field by field
+	wireDelta    // integer slice written as varint deltas
+	wireXORFloat // float slice written as a XOR compressed bit stream
+	wireBits     // integer or bool packed with the neighbouring bitfields
+	wireFixed    // integer written in a fixed number of bytes
+	wireCounted  // string or slice whose length is held by an earlier field
+	wireSized    // string or []byte padded with zeros to a fixed size
+	wireCString  // string or []byte followed by a zero byte
+)
+
+// wire returns the wire representation of the described type.
+func (s *Schema) wire() wireKind {
+	if s.Marshaler || tagOptions(s.Tag).Has("encrypt") {
+		return wireBytes
+	}
+	if s.bitWidth() > 0 {
+		return wireBits
+	}
+	if s.fixedWidth() > 0 {
+		return wireFixed
+	}
+	if s.countField() != "" && (s.Kind == K.String || s.Kind == K.Slice) {
+		return wireCounted
+	}
+	if s.byteSize() > 0 {
+		return wireSized
+	}
+	if s.cstring() {
+		return wireCString
+	}
+
+	switch s.Kind {
+	case K.Bool:
+		return wireBool
+	case K.Int, K.Int8, K.Int16, K.Int32, K.Int64:
+		return wireVarint
+	case K.Uint, K.Uint8, K.Uint16, K.Uint32, K.Uint64:
+		return wireUvarint
+	case K.Float32:
+		return wireFloat32
+	case K.Float64:
+		return wireFloat64
+	case K.String:
+		return wireBytes
+	case K.Slice:
+		// Delta coding also applies to []uint8, which is raw bytes otherwise
+		if tagOptions(s.Tag).Has("delta") {
+			return wireDelta
+		}
+		if s.Elem != nil && s.Elem.Kind == K.Uint8 {
+			return wireBytes
+		}
+		if tagOptions(s.Tag).Has("xorfloat") {
+			return wireXORFloat
+		}
+		if tagOptions(s.Tag).Has("columnar") {
+			return wireColumnar
+		}
+		return wireSlice
+	case K.Array:
+		return wireArray
+	case K.Pointer:
+		return wirePointer
+	case K.Struct:
+		return wireStruct
+	}
+	return wireInvalid
+}
+
+// ------------------------------------------------------------------------------
+
+// MarshalBinary implements encoding.BinaryMarshaler so schema snapshots can be
+// persisted and later compared against the current types.
+func (s *Schema) MarshalBinary() ([]byte, error) {
+	var buffer bytes.Buffer
+	e := &encoder{out: &buffer}
+	s.encodeTo(e)
+	return buffer.Bytes(), e.err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *Schema) UnmarshalBinary(data []byte) error {
+	d := &decoder{reader: newSliceReader(data)}
+	return s.decodeFrom(d)
+}
+
+func (s *Schema) encodeTo(e *encoder) {
+	e.WriteString(s.Name)
+	e.WriteString(s.Type)
+	e.WriteUvarint(uint64(s.Kind))
+	e.WriteString(s.Tag)
+	e.WriteUvarint(uint64(s.Len))
+	e.writeBool(s.Marshaler)
+	e.writeBool(s.Elem != nil)
+	if s.Elem != nil {
+		s.Elem.encodeTo(e)
+	}
+	e.WriteUvarint(uint64(len(s.Fields)))
+	for i := range s.Fields {
+		s.Fields[i].encodeTo(e)
+	}
+}
+
+func (s *Schema) decodeFrom(d *decoder) (err error) {
+	var n uint64
+	if s.Name, err = d.ReadString(); err != nil {
+		return err
+	}
+	if s.Type, err = d.ReadString(); err != nil {
+		return err
+	}
+	if n, err = d.ReadUvarint(); err != nil {
+		return err
+	}
+	s.Kind = Kind(n)
+	if s.Tag, err = d.ReadString(); err != nil {
+		return err
+	}
+	if n, err = d.ReadUvarint(); err != nil {
+		return err
+	}
+	s.Len = int(n)
+	if s.Marshaler, err = d.ReadBool(); err != nil {
+		return err
+	}
+
+	var hasElem bool
+	if hasElem, err = d.ReadBool(); err != nil {
+		return err
+	}
+	if hasElem {
+		s.Elem = new(Schema)
+		if err = s.Elem.decodeFrom(d); err != nil {
+			return err
+		}
+	}
+
+	if n, err = d.ReadUvarint(); err != nil {
+		return err
+	}
+	if r, ok := d.reader.(*sliceReader); ok && n > uint64(r.Len()) {
+		// Every field takes a few bytes, so a larger count is corrupted
+		return Err("schema", D.Field, n, D.Invalid)
+	}
+	if n > 0 {
+		s.Fields = make([]Schema, int(n))
+		for i := range s.Fields {
+			if err = s.Fields[i].decodeFrom(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package tinybin
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+func TestSchemaDescribesFixture(t *testing.T) {
+	tb := New()
+	s, err := tb.Schema(&FixtureComplex{})
+	assertNoError(t, err)
+
+	assertEqual(t, K.Struct, s.Kind)
+	assertEqualInt(t, 5, len(s.Fields))
+	assertEqual(t, "Secondary", s.Fields[2].Name)
+	assertEqual(t, K.Pointer, s.Fields[2].Kind)
+	assertEqual(t, K.Struct, s.Fields[2].Elem.Kind)
+	assertEqual(t, 3, s.Fields[4].Len)
+	assertEqual(t, K.Int, s.Fields[4].Elem.Kind)
+}
+
+func TestSchemaSkipsIgnoredFields(t *testing.T) {
+	type withSkip struct {
+		A int
+		B int `binary:"-"`
+		C string
+	}
+
+	s, err := New().Schema(withSkip{})
+	assertNoError(t, err)
+	assertEqualInt(t, 2, len(s.Fields))
+	assertEqual(t, "C", s.Fields[1].Name)
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	tb := New()
+	s, err := tb.Schema(&FixtureComplex{})
+	assertNoError(t, err)
+
+	b, err := tb.Encode(&s)
+	assertNoError(t, err)
+
+	var out Schema
+	assertNoError(t, tb.Decode(b, &out))
+	if !reflect.DeepEqual(s, out) {
+		t.Errorf("Expected %+v, got %+v", s, out)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	type v1 struct {
+		ID    int32
+		Name  string
+		Data  []byte
+		Score float32
+		Next  *int
+		Tags  []string
+	}
+
+	type compatible struct {
+		ID    int64
+		Name  []byte
+		Data  string
+		Score float32
+		Next  *int
+		Tags  []string
+	}
+
+	type breaking struct {
+		Name  string
+		ID    int64
+		Score float64
+		Next  int
+		Tags  []int
+		Extra bool
+	}
+
+	tb := New()
+	old, err := tb.Schema(v1{})
+	assertNoError(t, err)
+
+	t.Run("Compatible", func(t *testing.T) {
+		cur, err := tb.Schema(compatible{})
+		assertNoError(t, err)
+		if out := CheckCompatibility(old, cur); len(out) != 0 {
+			t.Errorf("Expected no incompatibilities, got %v", out)
+		}
+	})
+
+	t.Run("Breaking", func(t *testing.T) {
+		cur, err := tb.Schema(breaking{})
+		assertNoError(t, err)
+
+		got := make(map[string]string)
+		for _, inc := range CheckCompatibility(old, cur) {
+			got[inc.Path] = inc.Reason
+		}
+
+		expected := map[string]string{
+			"Name":   "field reordered",
+			"Data":   "field removed",
+			"Score":  "wire type changed",
+			"Next":   "pointer nullability changed",
+			"Tags[]": "wire type changed",
+			"Extra":  "field added",
+		}
+		assertEqual(t, expected, got)
+	})
+}
+
+func TestSchemaRejectsCorruptedFieldCount(t *testing.T) {
+	// An empty struct schema claiming 2^32 fields
+	data := []byte{0, 0, byte(K.Struct), 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}
+
+	var s Schema
+	if err := s.UnmarshalBinary(data); err == nil {
+		t.Error("Expected error for corrupted field count")
+	}
+}
@@ -0,0 +1,132 @@
+package tinybin
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// Signer signs the bytes of an envelope.
+type Signer interface {
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier checks the signature of an envelope. It returns an error when the
+// signature does not match the message.
+type Verifier interface {
+	Verify(message, signature []byte) error
+}
+
+// ErrSignature is returned by DecodeVerified when the signature does not match
+// the envelope. No codec runs on an envelope that fails verification.
+var ErrSignature = Err(D.Binary, "signature", D.Invalid)
+
+// EncodeSigned encodes the value into a signed envelope: the uvarint length of
+// the payload, the payload as produced by Encode and the signature of both.
+func (tb *TinyBin) EncodeSigned(v any, signer Signer) ([]byte, error) {
+	if signer == nil {
+		return nil, Err("EncodeSigned", "signer", D.Nil)
+	}
+
+	payload, err := tb.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix [10]byte
+	n := putUvarint(prefix[:], uint64(len(payload)))
+	signed := make([]byte, 0, n+len(payload)+ed25519.SignatureSize)
+	signed = append(signed, prefix[:n]...)
+	signed = append(signed, payload...)
+
+	signature, err := signer.Sign(signed)
+	if err != nil {
+		return nil, err
+	}
+	return append(signed, signature...), nil
+}
+
+// DecodeVerified verifies a signed envelope written by EncodeSigned and decodes
+// its payload into target. Nothing is decoded unless verification passes.
+func (tb *TinyBin) DecodeVerified(data []byte, target any, verifier Verifier) error {
+	if verifier == nil {
+		return Err("DecodeVerified", "verifier", D.Nil)
+	}
+
+	r := newSliceReader(data)
+	l, err := r.ReadUvarint()
+	if err != nil || l > uint64(r.Len()) {
+		return ErrSignature
+	}
+
+	end := len(data) - r.Len() + int(l)
+	if verifier.Verify(data[:end], data[end:]) != nil {
+		return ErrSignature
+	}
+	return tb.Decode(data[len(data)-r.Len():end], target)
+}
+
+// ------------------------------------------------------------------------------
+
+// Ed25519Signer signs envelopes with an Ed25519 private key.
+type Ed25519Signer struct {
+	Key ed25519.PrivateKey
+}
+
+// Sign implements Signer.
+func (s Ed25519Signer) Sign(message []byte) ([]byte, error) {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return nil, Err("ed25519", "private key", D.Invalid)
+	}
+	return ed25519.Sign(s.Key, message), nil
+}
+
+// Ed25519Verifier verifies envelopes with an Ed25519 public key.
+type Ed25519Verifier struct {
+	Key ed25519.PublicKey
+}
+
+// Verify implements Verifier.
+func (v Ed25519Verifier) Verify(message, signature []byte) error {
+	if len(v.Key) != ed25519.PublicKeySize || !ed25519.Verify(v.Key, message, signature) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// HMAC signs and verifies envelopes with a shared secret, using HMAC-SHA256
+// unless another hash is given.
+type HMAC struct {
+	Key  []byte
+	Hash func() hash.Hash // nil selects sha256.New
+}
+
+// Sign implements Signer.
+func (h HMAC) Sign(message []byte) ([]byte, error) {
+	if len(h.Key) == 0 {
+		return nil, Err("hmac", "key", D.Empty)
+	}
+	newHash := h.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	mac := hmac.New(newHash, h.Key)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Verify implements Verifier.
+func (h HMAC) Verify(message, signature []byte) error {
+	expected, err := h.Sign(message)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrSignature
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tinybin
+
+import (
+	"crypto/ed25519"
+	"crypto/sha512"
+	"testing"
+)
+
+func TestSignedEd25519(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	assertNoError(t, err)
+	tb := New()
+
+	in := basicStruct{Name: "config", Age: 3}
+	data, err := tb.EncodeSigned(&in, Ed25519Signer{Key: private})
+	assertNoError(t, err)
+
+	var out basicStruct
+	assertNoError(t, tb.DecodeVerified(data, &out, Ed25519Verifier{Key: public}))
+	assertEqual(t, in, out)
+
+	// A different key must be rejected
+	other, _, err := ed25519.GenerateKey(nil)
+	assertNoError(t, err)
+	if err := tb.DecodeVerified(data, &out, Ed25519Verifier{Key: other}); err != ErrSignature {
+		t.Fatalf("Expected ErrSignature, got %v", err)
+	}
+}
+
+func TestSignedHMAC(t *testing.T) {
+	tb := New(ChecksumCastagnoli)
+	mac := HMAC{Key: []byte("shared secret")}
+
+	data, err := tb.EncodeSigned(&basicStruct{Name: "x"}, mac)
+	assertNoError(t, err)
+
+	var out basicStruct
+	assertNoError(t, tb.DecodeVerified(data, &out, mac))
+	assertEqual(t, "x", out.Name)
+
+	if err := tb.DecodeVerified(data, &out, HMAC{Key: []byte("shared secret"), Hash: sha512.New}); err != ErrSignature {
+		t.Fatalf("Expected ErrSignature with another hash, got %v", err)
+	}
+}
+
+func TestSignedTampered(t *testing.T) {
+	tb := New()
+	mac := HMAC{Key: []byte("k")}
+	data, err := tb.EncodeSigned(&basicStruct{Name: "hello", Age: 1}, mac)
+	assertNoError(t, err)
+
+	for i := range data {
+		tampered := append([]byte(nil), data...)
+		tampered[i] ^= 0x01
+
+		out := basicStruct{Name: "untouched"}
+		if err := tb.DecodeVerified(tampered, &out, mac); err != ErrSignature {
+			t.Fatalf("byte %d: expected ErrSignature, got %v", i, err)
+		}
+		assertEqual(t, "untouched", out.Name)
+	}
+
+	var out basicStruct
+	if err := tb.DecodeVerified(data[:len(data)-1], &out, mac); err != ErrSignature {
+		t.Fatalf("Expected ErrSignature for truncated envelope, got %v", err)
+	}
+	if err := tb.DecodeVerified(nil, &out, mac); err != ErrSignature {
+		t.Fatalf("Expected ErrSignature for empty envelope, got %v", err)
+	}
+}
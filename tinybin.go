@@ -15,6 +15,39 @@ type TinyBin struct {
 	// log is an optional custom logging function
 	log func(msg ...any)
 
+	// checksum selects the integrity trailer appended to payloads
+	checksum Checksum
+
+	// maxMessage bounds the length of payloads read from streams
+	maxMessage int
+
+	// compression configures the optional payload compression
+	compression Compression
+
+	// keys seals the fields tagged `binary:"encrypt"`
+	keys KeyProvider
+
+	// canonical makes the encoding deterministic
+	canonical bool
+
+	// autoDelta lets integer slices be delta coded when smaller
+	autoDelta bool
+
+	// bitPacking lets integer slices be bit packed when smaller
+	bitPacking bool
+
+	// fixedWidth writes integers at the natural size of their kind
+	fixedWidth bool
+
+	// bigEndian writes fixed size values most significant byte first
+	bigEndian bool
+
+	// align pads struct fields to their natural alignment
+	align bool
+
+	// stringDict writes repeated strings as references to a per-message table
+	stringDict bool
+
 	// schemas is a slice-based cache for TinyGo compatibility (no maps allowed)
 	schemas []schemaEntry
 
@@ -24,7 +57,19 @@ type TinyBin struct {
 	// decoders is a private pool for decoder instances
 	decoders *sync.Pool
 
-	// Mutex to protect schemas slice
+	// versions maps message versions to their registered types
+	versions []versionEntry
+
+	// legacyVersion is the version of payloads written without a header
+	legacyVersion uint32
+
+	// migrations holds the registered version migrations
+	migrations []migrationEntry
+
+	// messages maps envelope message ids to their registered types
+	messages []messageEntry
+
+	// Mutex to protect the schemas and registry slices
 	mu sync.RWMutex
 }
 
@@ -35,21 +80,44 @@ type schemaEntry struct {
 }
 
 // New creates a new TinyBin instance with optional configuration.
-// The first argument can be an optional logging function.
+// The arguments can be an optional logging function, a Checksum and a Compression.
 // If no logging function is provided, a no-op logger is used.
-// eg: tb := tinybin.New(func(msg ...any) { fmt.Println(msg...) })
+// eg: tb := tinybin.New(func(msg ...any) { fmt.Println(msg...) }, tinybin.ChecksumIEEE)
 
 func New(args ...any) *TinyBin {
-	var logFunc func(msg ...any) // Default: no logging
+	tb := &TinyBin{} // Default: no logging, no checksum
 
 	for _, arg := range args {
-		if log, ok := arg.(func(msg ...any)); ok {
-			logFunc = log
+		switch v := arg.(type) {
+		case func(msg ...any):
+			tb.log = v
+		case Checksum:
+			tb.checksum = v
+		case MaxMessageSize:
+			tb.maxMessage = int(v)
+		case Compression:
+			tb.compression = v
+		case LegacyVersion:
+			tb.legacyVersion = uint32(v)
+		case KeyProvider:
+			tb.keys = v
+		case Canonical:
+			tb.canonical = true
+		case AutoDelta:
+			tb.autoDelta = true
+		case BitPacking:
+			tb.bitPacking = true
+		case StringDictionary:
+			tb.stringDict = true
+		case FixedWidth:
+			tb.fixedWidth = true
+		case CLayout:
+			tb.fixedWidth = true
+			tb.bigEndian = v.BigEndian
+			tb.align = v.Align
 		}
 	}
 
-	tb := &TinyBin{log: logFunc}
-
 	tb.schemas = make([]schemaEntry, 0, 100) // Pre-allocate reasonable size
 	tb.encoders = &sync.Pool{
 		New: func() any {
@@ -84,6 +152,20 @@ func (tb *TinyBin) Encode(data any) ([]byte, error) {
 
 // EncodeTo encodes the payload into a specific destination using this TinyBin instance.
 func (tb *TinyBin) EncodeTo(data any, dst io.Writer) error {
+	// The checksum covers the whole payload, so it needs to be encoded first
+	if tb.checksum != ChecksumNone {
+		var buffer bytes.Buffer
+		if err := tb.encodeTo(data, &buffer); err != nil {
+			return err
+		}
+		return tb.writeChecked(buffer.Bytes(), dst)
+	}
+
+	return tb.encodeTo(data, dst)
+}
+
+// encodeTo runs the codecs of the payload with a pooled encoder.
+func (tb *TinyBin) encodeTo(data any, dst io.Writer) error {
 	// Get the encoder from the pool, reset it
 	e := tb.encoders.Get().(*encoder)
 	e.Reset(dst, tb)
@@ -98,6 +180,37 @@ func (tb *TinyBin) EncodeTo(data any, dst io.Writer) error {
 
 // Decode decodes the payload from the binary format using this TinyBin instance.
 func (tb *TinyBin) Decode(data []byte, target any) error {
+	// Verify the integrity trailer before running any codec
+	if tb.checksum != ChecksumNone {
+		body, err := tb.openChecked(data)
+		if err != nil {
+			return err
+		}
+		data = body
+	}
+
+	return tb.decode(data, target)
+}
+
+// DecodeFrom decodes a single payload read from a stream. Pass a reader
+// implementing io.ByteReader (such as a bufio.Reader) when decoding several
+// payloads from the same stream.
+func (tb *TinyBin) DecodeFrom(r io.Reader, target any) error {
+	rdr := newReader(r)
+	if tb.checksum != ChecksumNone {
+		body, err := tb.readChecked(rdr)
+		if err != nil {
+			return err
+		}
+		return tb.decode(body, target)
+	}
+
+	d := &decoder{reader: rdr, tb: tb}
+	return d.Decode(target)
+}
+
+// decode runs the codecs of the payload with a pooled decoder.
+func (tb *TinyBin) decode(data []byte, target any) error {
 	// Get the decoder from the pool, reset it
 	d := tb.decoders.Get().(*decoder)
 	d.Reset(data, tb)
@@ -0,0 +1,210 @@
+package tinybin
+
+import (
+	"io"
+	"math"
+	"math/bits"
+	"reflect"
+	"unsafe"
+
+	. "github.com/cdvelop/tinystring"
+)
+
+// xorFloatCodec compresses float slices tagged with `binary:"xorfloat"` as in
+// Facebook's Gorilla: every value is XORed with the previous one and only the
+// meaningful bits of the result are stored. On the wire the slice is the
+// uvarint length followed by the uvarint byte length of the bit stream and the
+// stream itself.
+//
+// The first value is stored with all its bits. Each following value is a 0 bit
+// when it repeats the previous value. Otherwise a 1 bit is followed by a 0 bit
+// and the meaningful bits when they fit the window of the previous value, or by
+// a 1 bit, 6 bits of leading zeros, 6 bits of length minus one and the bits.
+type xorFloatCodec struct {
+	width uint // 32 or 64
+}
+
+// newXORFloatCodec returns the XOR codec of a float slice.
+func newXORFloatCodec(t reflect.Type) (Codec, error) {
+	if t.Kind() == reflect.Slice {
+		switch t.Elem().Kind() {
+		case reflect.Float32:
+			return &xorFloatCodec{width: 32}, nil
+		case reflect.Float64:
+			return &xorFloatCodec{width: 64}, nil
+		}
+	}
+	return nil, Err("xorfloat", D.Type, t.String(), D.Not, D.Supported)
+}
+
+// Encode encodes a value into the encoder.
+func (c *xorFloatCodec) EncodeTo(e *encoder, rv reflect.Value) error {
+	l := rv.Len()
+	e.WriteUvarint(uint64(l))
+	if l == 0 {
+		return nil
+	}
+
+	var w bitWriter
+	var prev uint64
+	leading, trailing := uint(c.width+1), uint(0) // no window yet
+	for i := 0; i < l; i++ {
+		v := c.floatBits(rv.Index(i))
+		if e.canonical {
+			v = c.canonical(v)
+		}
+		if i == 0 {
+			w.writeBits(v, c.width)
+			prev = v
+			continue
+		}
+
+		xor := v ^ prev
+		prev = v
+		if xor == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+
+		lz := uint(bits.LeadingZeros64(xor)) - (64 - c.width)
+		tz := uint(bits.TrailingZeros64(xor))
+		if leading <= c.width && lz >= leading && tz >= trailing {
+			// Reuse the window of the previous value
+			w.writeBit(false)
+			w.writeBits(xor>>trailing, c.width-leading-trailing)
+			continue
+		}
+
+		leading, trailing = lz, tz
+		meaningful := c.width - lz - tz
+		w.writeBit(true)
+		w.writeBits(uint64(lz), 6)
+		w.writeBits(uint64(meaningful-1), 6)
+		w.writeBits(xor>>tz, meaningful)
+	}
+
+	e.WriteUvarint(uint64(len(w.buf)))
+	e.Write(w.buf)
+	return nil
+}
+
+// Decode decodes into a reflect value from the decoder.
+func (c *xorFloatCodec) DecodeTo(d *decoder, rv reflect.Value) error {
+	l, err := d.ReadUvarint()
+	if err != nil || l == 0 {
+		return err
+	}
+	stream, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+
+	rv.Set(reflect.MakeSlice(rv.Type(), int(l), int(l)))
+	return c.decodeStream(stream, int(l), func(i int, v uint64) {
+		c.setFloatBits(rv.Index(i), v)
+	})
+}
+
+// decodeStream decodes n values from the bit stream.
+func (c *xorFloatCodec) decodeStream(stream []byte, n int, set func(i int, v uint64)) error {
+	r := bitReader{buf: stream}
+	prev, err := r.readBits(c.width)
+	if err != nil {
+		return err
+	}
+	set(0, prev)
+
+	var leading, trailing uint
+	for i := 1; i < n; i++ {
+		changed, err := r.readBit()
+		if err != nil {
+			return err
+		}
+		if changed {
+			fresh, err := r.readBit()
+			if err != nil {
+				return err
+			}
+			if fresh {
+				lz, err := r.readBits(6)
+				if err != nil {
+					return err
+				}
+				meaningful, err := r.readBits(6)
+				if err != nil {
+					return err
+				}
+				if uint(lz)+uint(meaningful)+1 > c.width {
+					return Err("xorfloat", D.Format, D.Invalid)
+				}
+				leading, trailing = uint(lz), c.width-uint(lz)-uint(meaningful)-1
+			}
+
+			xor, err := r.readBits(c.width - leading - trailing)
+			if err != nil {
+				return err
+			}
+			prev ^= xor << trailing
+		}
+		set(i, prev)
+	}
+	return nil
+}
+
+// floatBits returns the raw bits of an addressable float, without going through
+// float64 so float32 NaN payloads are kept exactly.
+func (c *xorFloatCodec) floatBits(rv reflect.Value) uint64 {
+	if c.width == 32 {
+		return uint64(*(*uint32)(unsafe.Pointer(rv.UnsafeAddr())))
+	}
+	return *(*uint64)(unsafe.Pointer(rv.UnsafeAddr()))
+}
+
+// setFloatBits stores raw bits into an addressable float.
+func (c *xorFloatCodec) setFloatBits(rv reflect.Value, v uint64) {
+	if c.width == 32 {
+		*(*uint32)(unsafe.Pointer(rv.UnsafeAddr())) = uint32(v)
+	} else {
+		*(*uint64)(unsafe.Pointer(rv.UnsafeAddr())) = v
+	}
+}
+
+// canonical returns the bits of the canonical form of a float.
+func (c *xorFloatCodec) canonical(v uint64) uint64 {
+	if c.width == 32 {
+		return uint64(math.Float32bits(canonicalFloat32(math.Float32frombits(uint32(v)))))
+	}
+	return math.Float64bits(canonicalFloat64(math.Float64frombits(v)))
+}
+
+// decodeFloatItems decodes a XOR compressed float slice without a Go type.
+func decodeFloatItems(d *decoder, s *Schema, v *Value) error {
+	l, err := d.ReadUvarint()
+	if err != nil || l == 0 {
+		return err
+	}
+	stream, err := d.ReadSlice()
+	if err != nil {
+		return err
+	}
+	if l > uint64(len(stream))*8 {
+		// Every value takes at least one bit of the stream
+		return io.ErrUnexpectedEOF
+	}
+
+	c := xorFloatCodec{width: 64}
+	if s.Elem.Kind == K.Float32 {
+		c.width = 32
+	}
+	v.Items = make([]Value, 0, min(l, 1024))
+	return c.decodeStream(stream, int(l), func(_ int, bits uint64) {
+		item := Value{Name: s.Elem.Name, Kind: s.Elem.Kind}
+		if c.width == 32 {
+			item.Scalar = math.Float32frombits(uint32(bits))
+		} else {
+			item.Scalar = math.Float64frombits(bits)
+		}
+		v.Items = append(v.Items, item)
+	})
+}
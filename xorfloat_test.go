@@ -0,0 +1,151 @@
+package tinybin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+type sensorBatch struct {
+	Temperature []float64 `binary:"xorfloat"`
+	Humidity    []float32 `binary:"xorfloat"`
+}
+
+func newSensorBatch(n int) sensorBatch {
+	var b sensorBatch
+	for i := 0; i < n; i++ {
+		b.Temperature = append(b.Temperature, 21.5+float64(i/10)*0.25)
+		b.Humidity = append(b.Humidity, 40+float32(i%3))
+	}
+	return b
+}
+
+func TestXORFloatRoundTrip(t *testing.T) {
+	tb := New()
+	in := newSensorBatch(1000)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out sensorBatch
+	assertNoError(t, tb.Decode(data, &out))
+	assertEqual(t, in, out)
+
+	plain, err := tb.Encode(&struct {
+		Temperature []float64
+		Humidity    []float32
+	}{in.Temperature, in.Humidity})
+	assertNoError(t, err)
+	if len(data)*4 >= len(plain) {
+		t.Errorf("Expected XOR compressed payload (%d bytes) to be far smaller than plain (%d bytes)", len(data), len(plain))
+	}
+}
+
+func TestXORFloatBitExact(t *testing.T) {
+	tb := New()
+	in := sensorBatch{
+		Temperature: []float64{
+			math.Float64frombits(0x7FF0000000000001), // signalling NaN
+			math.NaN(),
+			math.Float64frombits(0xFFF8DEADBEEF0000), // negative NaN with payload
+			math.Copysign(0, -1), 0, math.Inf(1), math.Inf(-1),
+			math.SmallestNonzeroFloat64, math.MaxFloat64, -1.5, -1.5,
+		},
+		Humidity: []float32{
+			math.Float32frombits(0x7F800001), // signalling NaN
+			math.Float32frombits(0xFFC01234),
+			float32(math.Copysign(0, -1)), 0, math.MaxFloat32, 1, 1,
+		},
+	}
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var out sensorBatch
+	assertNoError(t, tb.Decode(data, &out))
+
+	assertEqualInt(t, len(in.Temperature), len(out.Temperature))
+	for i := range in.Temperature {
+		if math.Float64bits(in.Temperature[i]) != math.Float64bits(out.Temperature[i]) {
+			t.Errorf("float64 %d: expected bits %x, got %x", i, math.Float64bits(in.Temperature[i]), math.Float64bits(out.Temperature[i]))
+		}
+	}
+	assertEqualInt(t, len(in.Humidity), len(out.Humidity))
+	for i := range in.Humidity {
+		if math.Float32bits(in.Humidity[i]) != math.Float32bits(out.Humidity[i]) {
+			t.Errorf("float32 %d: expected bits %x, got %x", i, math.Float32bits(in.Humidity[i]), math.Float32bits(out.Humidity[i]))
+		}
+	}
+}
+
+func TestXORFloatEdgeCases(t *testing.T) {
+	tb := New()
+	for _, in := range []sensorBatch{
+		{},
+		{Temperature: []float64{3.14}, Humidity: []float32{2.71}},
+		{Temperature: []float64{1, 1, 1, 1}},
+	} {
+		data, err := tb.Encode(&in)
+		assertNoError(t, err)
+		var out sensorBatch
+		assertNoError(t, tb.Decode(data, &out))
+		assertEqual(t, in, out)
+	}
+
+	type invalid struct {
+		Values []int64 `binary:"xorfloat"`
+	}
+	if _, err := tb.Encode(&invalid{}); err == nil {
+		t.Error("Expected error for XOR compressed integer slice")
+	}
+}
+
+func TestXORFloatTruncated(t *testing.T) {
+	tb := New()
+	data, err := tb.Encode(&sensorBatch{Temperature: []float64{1, 2, 3, 4}})
+	assertNoError(t, err)
+
+	// Claim more values than the stream holds
+	data[0] = 100
+	var out sensorBatch
+	if err := tb.Decode(data, &out); err == nil {
+		t.Fatal("Expected error for truncated stream")
+	}
+
+	// Decoding without a Go type does not trust the count either
+	schema, err := tb.Schema(sensorBatch{})
+	assertNoError(t, err)
+	huge := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F, 8, 0, 0, 0, 0, 0, 0, 0xF0, 0x3F, 0}
+	if _, err := decodeValue(&decoder{reader: newSliceReader(huge), tb: tb}, &schema); err == nil {
+		t.Error("Expected error for a corrupted count decoded without a Go type")
+	}
+}
+
+func TestXORFloatSchema(t *testing.T) {
+	tb := New()
+	schema, err := tb.Schema(sensorBatch{})
+	assertNoError(t, err)
+	assertEqual(t, wireXORFloat, schema.Fields[0].wire())
+
+	in := newSensorBatch(20)
+	var file bytes.Buffer
+	w, err := tb.NewContainerWriter(&file, sensorBatch{})
+	assertNoError(t, err)
+	assertNoError(t, w.Append(&in))
+	assertNoError(t, w.Close())
+
+	r, err := tb.NewContainerReader(&file)
+	assertNoError(t, err)
+	v, err := r.NextValue()
+	assertNoError(t, err)
+	temperature, _ := v.Field("Temperature")
+	assertEqual(t, in.Temperature[15], temperature.Items[15].Scalar)
+	humidity, _ := v.Field("Humidity")
+	assertEqual(t, in.Humidity[7], humidity.Items[7].Scalar)
+
+	data, err := tb.Encode(&in)
+	assertNoError(t, err)
+	var humidityOnly struct {
+		Humidity []float32 `binary:"xorfloat"`
+	}
+	assertNoError(t, tb.DecodeWithWriterSchema(data, schema, &humidityOnly))
+	assertEqual(t, in.Humidity, humidityOnly.Humidity)
+}